@@ -3,13 +3,14 @@ module github.com/crockeo/schoner
 go 1.20
 
 require (
+	github.com/alecthomas/kong v0.8.0
+	github.com/bmatcuk/doublestar/v4 v4.6.1
 	github.com/goccy/go-graphviz v0.1.1
 	github.com/stretchr/testify v1.8.4
 	golang.org/x/mod v0.12.0
 )
 
 require (
-	github.com/alecthomas/kong v0.8.0 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/fogleman/gg v1.3.0 // indirect
 	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0 // indirect
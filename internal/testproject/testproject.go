@@ -0,0 +1,29 @@
+// Package testproject writes small Go projects for tests to analyze.
+package testproject
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// GoMod is the go.mod written by Write, unless the project provides its own.
+const GoMod = "module example.com/root\n"
+
+// Write writes `files`, keyed by their paths relative to the project root, to a new temporary directory,
+// and returns its root. The project is a module named example.com/root unless `files` contains a go.mod.
+func Write(t testing.TB, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	if _, ok := files["go.mod"]; !ok {
+		require.NoError(t, os.WriteFile(filepath.Join(root, "go.mod"), []byte(GoMod), 0o644))
+	}
+	for filename, contents := range files {
+		path := filepath.Join(root, filename)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(contents), 0o644))
+	}
+	return root
+}
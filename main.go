@@ -1,17 +1,26 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/alecthomas/kong"
-	"github.com/crockeo/schoner/pkg/astutil"
-	"github.com/crockeo/schoner/pkg/graph"
+	"github.com/crockeo/schoner/pkg/api"
+	"github.com/crockeo/schoner/pkg/lsp"
 	"github.com/crockeo/schoner/pkg/phases/fileinfo"
-	"github.com/crockeo/schoner/pkg/phases/references"
+	"github.com/crockeo/schoner/pkg/phases/interfaces"
+	"github.com/crockeo/schoner/pkg/phases/params"
+	"github.com/crockeo/schoner/pkg/project"
+	"github.com/crockeo/schoner/pkg/rules"
 	"github.com/crockeo/schoner/pkg/set"
 	"github.com/crockeo/schoner/pkg/visualize"
 	"github.com/crockeo/schoner/pkg/walk"
@@ -28,17 +37,88 @@ func main() {
 type args struct {
 	Visualize   visualizeArgs   `cmd:"" help:"Visualize references in a project."`
 	Unreachable unreachableArgs `cmd:"" help:"List all unreachable declarations in a project."`
+	Impact      impactArgs      `cmd:"" help:"Report how much code would become unreachable if each declaration were removed."`
+	Params      paramsArgs      `cmd:"" help:"List function parameters which are never read, and results which every caller discards."`
+	Methods     methodsArgs     `cmd:"" help:"Explain why methods which are never referenced directly are reachable, e.g. to implement an interface."`
+	Serve       serveArgs       `cmd:"" help:"Serve reachability queries about a project over HTTP, re-analyzing it whenever it changes."`
+	LSP         lspArgs         `cmd:"" name:"lsp" help:"Run a language server over stdio which reports unreachable declarations as diagnostics."`
+}
+
+// analysisArgs configures how each project is analyzed, and is shared by every command which analyzes one.
+type analysisArgs struct {
+	Loader    string   `name:"loader" enum:"walk,packages" default:"walk" help:"How to find the files of a project: walk the filesystem, or ask the go tool with go list."`
+	Include   []string `name:"include" sep:"none" help:"Only analyze files matching one of these glob patterns, e.g. 'pkg/**'. Patterns without a slash match file names at any depth."`
+	Exclude   []string `name:"exclude" sep:"none" help:"Skip files and directories matching any of these glob patterns, e.g. 'gen/**' or '*.pb.go'."`
+	Gitignore bool     `name:"gitignore" default:"true" negatable:"" help:"Skip files and directories ignored by a .gitignore."`
+	Generated string   `name:"generated" enum:"report,hide,entrypoints" default:"report" help:"How to treat files with a 'Code generated ... DO NOT EDIT.' header: report them like any other file, hide their unreachable declarations, or treat their exported declarations as entrypoints."`
+	Rules     bool     `name:"rules" default:"true" negatable:"" help:"Keep declarations which are used by convention: methods the standard library calls, like String() string, and framework declarations, like wire provider sets."`
+}
+
+func (aa analysisArgs) options() project.Option {
+	return project.WithOptions(
+		project.WithLoader(project.Loader(aa.Loader)),
+		project.WithGenerated(project.GeneratedMode(aa.Generated)),
+		project.WithRules(aa.rules()...),
+		project.WithWalkOptions(
+			walk.WithInclude(aa.Include...),
+			walk.WithExclude(aa.Exclude...),
+			walk.WithGitignore(aa.Gitignore),
+		),
+	)
+}
+
+func (aa analysisArgs) rules() []rules.Rule {
+	if !aa.Rules {
+		return nil
+	}
+	return rules.Builtin
 }
 
 type visualizeArgs struct {
-	OutputDir string   `name:"output-dir" help:"The directory in which .svg files will be generated."`
-	Paths     []string `arg:"" name:"path" help:"List of projects to visualize." type:"path"`
+	analysisArgs `embed:""`
+	OutputDir    string   `name:"output-dir" help:"The directory in which visualizations will be generated, which is created if it doesn't exist. Use - to write to stdout."`
+	Format       string   `name:"format" enum:"svg,html,dot,graphml,mermaid,json" default:"svg" help:"The format of the generated visualizations: svg, an interactive html page, or one of the graph data formats dot, graphml, mermaid, or json."`
+	Collapse     string   `name:"collapse" enum:"none,packages" default:"none" help:"Render one node per package instead of one per declaration. Only supported by svg and dot."`
+	Focus        string   `name:"focus" help:"Only render the declarations near this symbol, e.g. Server::Start or pkg/server.go::Server::Start."`
+	Depth        int      `name:"depth" default:"2" help:"How many references away from --focus to render."`
+	Direction    string   `name:"direction" enum:"in,out,both" default:"both" help:"Whether to render what --focus references (out), what references it (in), or both."`
+	Paths        []string `arg:"" name:"path" help:"List of projects to visualize." type:"path"`
 }
 
 type unreachableArgs struct {
-	Paths []string `arg:"" name:"path" help:"List of projects to analyze." type:"path"`
+	analysisArgs `embed:""`
+	Clusters     bool     `name:"clusters" help:"Group unreachable declarations into dead clusters which can be deleted as a unit."`
+	Collapse     bool     `name:"collapse" help:"Report packages and files whose declarations are all unreachable as a whole, instead of listing their declarations."`
+	Paths        []string `arg:"" name:"path" help:"List of projects to analyze." type:"path"`
+}
+
+type impactArgs struct {
+	analysisArgs `embed:""`
+	Limit        int      `name:"limit" default:"20" help:"Maximum number of declarations to report. 0 reports every reachable declaration."`
+	Verbose      bool     `name:"verbose" short:"v" help:"List the declarations which each declaration exclusively keeps alive."`
+	Paths        []string `arg:"" name:"path" help:"List of projects to analyze." type:"path"`
+}
+
+type paramsArgs struct {
+	analysisArgs `embed:""`
+	Paths        []string `arg:"" name:"path" help:"List of projects to analyze." type:"path"`
 }
 
+type methodsArgs struct {
+	analysisArgs `embed:""`
+	DeadOnly     bool     `name:"dead-only" help:"Only list methods which implement interfaces that are never used."`
+	Paths        []string `arg:"" name:"path" help:"List of projects to analyze." type:"path"`
+}
+
+type serveArgs struct {
+	analysisArgs `embed:""`
+	Addr         string        `name:"addr" default:"localhost:8080" help:"The address on which to listen."`
+	Interval     time.Duration `name:"interval" default:"2s" help:"How often to check whether the project has changed."`
+	Path         string        `arg:"" name:"path" help:"The project to serve." type:"path"`
+}
+
+type lspArgs struct{}
+
 func mainImpl() error {
 	args := args{}
 	ctx := kong.Parse(&args)
@@ -47,116 +127,521 @@ func mainImpl() error {
 		return visualizeMain(args.Visualize)
 	case "unreachable <path>":
 		return unreachableMain(args.Unreachable)
+	case "impact <path>":
+		return impactMain(args.Impact)
+	case "params <path>":
+		return paramsMain(args.Params)
+	case "methods <path>":
+		return methodsMain(args.Methods)
+	case "serve <path>":
+		return serveMain(args.Serve)
+	case "lsp":
+		return lsp.NewServer(os.Stdin, os.Stdout).Serve()
 	default:
 		panic("unreachable")
 	}
 }
 
 func visualizeMain(args visualizeArgs) error {
-	outputDir, err := filepath.Abs(args.OutputDir)
-	if err != nil {
-		return err
+	if args.Collapse != "none" && args.Format != "svg" && args.Format != "dot" {
+		return fmt.Errorf("--collapse %s is only supported with --format svg or dot", args.Collapse)
+	}
+
+	toStdout := args.OutputDir == "-"
+	if toStdout && len(args.Paths) > 1 {
+		return fmt.Errorf("--output-dir - only supports visualizing a single path, got %d", len(args.Paths))
+	}
+	outputDir := ""
+	if !toStdout {
+		var err error
+		outputDir, err = filepath.Abs(args.OutputDir)
+		if err != nil {
+			return err
+		}
+		if err := ensureDirectory(outputDir); err != nil {
+			return err
+		}
 	}
-	// TODO: check that outputDir is actually a directory
 
 	for _, path := range args.Paths {
 		path, err := filepath.Abs(path)
 		if err != nil {
 			return err
 		}
-		// TODO: check that path is a directory
+		if err := checkDirectory(path); err != nil {
+			return err
+		}
 
-		analysis, err := analyzeProject(path)
+		analysis, err := project.Analyze(path, args.options())
 		if err != nil {
 			return err
 		}
-		visualize.Visualize(
-			fmt.Sprintf("%s.svg", filepath.Join(outputDir, filepath.Base(path))),
-			analysis.ReferenceGraph,
-			analysis.Entrypoints,
-			analysis.Unreachable,
-		)
+		referenceGraph := analysis.ReferenceGraph
+		if args.Focus != "" {
+			referenceGraph, err = analysis.Focus(args.Focus, args.Depth, project.Direction(args.Direction))
+			if err != nil {
+				return err
+			}
+		}
+
+		render := func(w io.Writer) error {
+			switch args.Format {
+			case "svg":
+				return visualize.Visualize(
+					w,
+					referenceGraph,
+					analysis.Entrypoints,
+					analysis.Unreachable,
+					visualize.WithCollapsePackages(args.Collapse == "packages"),
+				)
+			case "html":
+				return visualize.VisualizeHTML(
+					w,
+					referenceGraph,
+					analysis.Entrypoints,
+					analysis.Unreachable,
+				)
+			default:
+				return visualize.Export(
+					w,
+					visualize.Format(args.Format),
+					referenceGraph,
+					analysis.Entrypoints,
+					analysis.Unreachable,
+					visualize.WithCollapsePackages(args.Collapse == "packages"),
+				)
+			}
+		}
+
+		if toStdout {
+			if err := render(os.Stdout); err != nil {
+				return fmt.Errorf("failed to visualize %s: %w", path, err)
+			}
+			continue
+		}
+		outputPath := fmt.Sprintf("%s.%s", filepath.Join(outputDir, filepath.Base(path)), args.Format)
+		if err := writeOutput(outputPath, render); err != nil {
+			return fmt.Errorf("failed to visualize %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// ensureDirectory creates the directory at `path` if it doesn't exist,
+// and fails if something other than a directory is already there.
+func ensureDirectory(path string) error {
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return os.MkdirAll(path, 0o755)
+	} else if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", path)
+	}
+	return nil
+}
+
+// checkDirectory fails unless `path` is an existing directory.
+func checkDirectory(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", path)
+	}
+	return nil
+}
+
+// writeOutput calls `render` with a temporary file beside `outputPath`,
+// and only replaces `outputPath` once rendering succeeds, so that a failed run never clobbers a previous result.
+func writeOutput(outputPath string, render func(io.Writer) error) error {
+	output, err := os.CreateTemp(filepath.Dir(outputPath), "."+filepath.Base(outputPath)+".*")
+	if err != nil {
+		return err
+	}
+	if err := renderTemp(output, render); err != nil {
+		output.Close()
+		os.Remove(output.Name())
+		return err
+	}
+	if err := output.Close(); err != nil {
+		os.Remove(output.Name())
+		return err
+	}
+	if err := os.Rename(output.Name(), outputPath); err != nil {
+		os.Remove(output.Name())
+		return err
 	}
 	return nil
 }
 
+// renderTemp renders into `output`, giving it the permissions os.Create would have,
+// since temporary files are only readable by their owner.
+func renderTemp(output *os.File, render func(io.Writer) error) error {
+	if err := output.Chmod(0o644); err != nil {
+		return err
+	}
+	return render(output)
+}
+
+func serveMain(args serveArgs) error {
+	path, err := filepath.Abs(args.Path)
+	if err != nil {
+		return err
+	}
+	if err := checkDirectory(path); err != nil {
+		return err
+	}
+
+	server, err := api.NewServer(path, args.options())
+	if err != nil {
+		return err
+	}
+	go server.Watch(context.Background(), args.Interval)
+
+	fmt.Printf("serving %s on %s\n", path, args.Addr)
+	return http.ListenAndServe(args.Addr, server.Handler())
+}
+
 func unreachableMain(args unreachableArgs) error {
 	for _, path := range args.Paths {
 		path, err := filepath.Abs(path)
 		if err != nil {
 			return err
 		}
-		// TODO: check that path is a directory
+		if err := checkDirectory(path); err != nil {
+			return err
+		}
 
-		analysis, err := analyzeProject(path)
+		analysis, err := project.Analyze(path, args.options())
 		if err != nil {
 			return err
 		}
 
-		unreachableByName := map[string]fileinfo.Declaration{}
-		unreachableNames := make([]string, 0, len(analysis.Unreachable))
-		for decl := range analysis.Unreachable {
-			filename := decl.Parent.Filename
-			if !strings.HasPrefix(filename, path) {
-				return fmt.Errorf("file %s does not begin with expected path %s", filename, path)
+		unreachable := analysis.Unreachable
+		if args.Collapse {
+			unreachable, err = printDeadPackagesAndFiles(analysis)
+			if err != nil {
+				return err
 			}
-			filename = filename[len(path):]
-			filename = strings.TrimPrefix(filename, "/")
-			unreachableName := astutil.Qualify(filename, decl.Name)
+		}
 
-			unreachableByName[unreachableName] = decl
-			unreachableNames = append(unreachableNames, unreachableName)
+		if args.Clusters {
+			if err := printDeadClusters(analysis, unreachable); err != nil {
+				return err
+			}
+			continue
+		}
+
+		unreachableNames, err := analysis.DeclarationNames(unreachable.ToSlice())
+		if err != nil {
+			return err
 		}
-		sort.Strings(unreachableNames)
 		for _, unreachableName := range unreachableNames {
 			fmt.Println(unreachableName)
 		}
-		return nil
 	}
 	return nil
 }
 
-type analysis struct {
-	FileInfos      map[string]*fileinfo.FileInfo
-	ReferenceGraph graph.Graph[fileinfo.Declaration]
-	Unreachable    set.Set[fileinfo.Declaration]
-	Entrypoints    set.Set[fileinfo.Declaration]
+// printDeadPackagesAndFiles prints the packages and files whose declarations are all unreachable,
+// and returns the unreachable declarations which do not belong to any of them.
+func printDeadPackagesAndFiles(analysis *project.Analysis) (set.Set[fileinfo.Declaration], error) {
+	remaining := set.NewSet[fileinfo.Declaration]()
+	remaining.UnionInPlace(analysis.Unreachable)
+
+	deadPackages := analysis.PackageGraph.DeadPackages(analysis.Unreachable)
+	deadPackagePaths := set.NewSet[string]()
+	for _, pkg := range deadPackages {
+		deadPackagePaths.Add(pkg.ImportPath)
+	}
+
+	packageLines := []string{}
+	for _, pkg := range deadPackages {
+		dir, err := analysis.RelativePath(filepath.Dir(pkg.Files[0].Filename))
+		if err != nil {
+			return nil, err
+		}
+		liveImporters := []string{}
+		for importer := range analysis.PackageGraph.Importers(pkg.ImportPath) {
+			if !deadPackagePaths.Contains(importer) {
+				liveImporters = append(liveImporters, importer)
+			}
+		}
+		sort.Strings(liveImporters)
+
+		line := fmt.Sprintf("dead package: %s", dir)
+		if len(liveImporters) > 0 {
+			line += fmt.Sprintf(" (still imported by %s)", strings.Join(liveImporters, ", "))
+		}
+		packageLines = append(packageLines, line)
+
+		for _, fileInfo := range pkg.Files {
+			for _, decl := range fileInfo.Declarations {
+				remaining.Remove(decl)
+			}
+		}
+	}
+	sort.Strings(packageLines)
+	for _, line := range packageLines {
+		fmt.Println(line)
+	}
+
+	fileLines := []string{}
+	for _, fileInfo := range analysis.PackageGraph.DeadFiles(analysis.Unreachable) {
+		filename, err := analysis.RelativePath(fileInfo.Filename)
+		if err != nil {
+			return nil, err
+		}
+		fileLines = append(fileLines, fmt.Sprintf("dead file: %s", filename))
+		for _, decl := range fileInfo.Declarations {
+			remaining.Remove(decl)
+		}
+	}
+	sort.Strings(fileLines)
+	for _, line := range fileLines {
+		fmt.Println(line)
+	}
+
+	return remaining, nil
 }
 
-func analyzeProject(path string) (analysis, error) {
-	// TODO: make these configurable?
-	walkOptions := walk.WithOptions(
-		walk.WithIgnoreDirs(".git"),
-		// walk.WithIgnoreTests(true),
-	)
+// printDeadClusters prints the strongly connected components of the unreachable declarations,
+// ordered such that each cluster can be deleted before any of the clusters it references.
+func printDeadClusters(analysis *project.Analysis, unreachable set.Set[fileinfo.Declaration]) error {
+	deadGraph := analysis.ReferenceGraph.Graph.Subgraph(unreachable)
+	components := deadGraph.StronglyConnectedComponents(fileinfo.Declaration.Less)
 
-	fileInfos, err := fileinfo.FindFileInfos(path, walkOptions)
-	if err != nil {
-		return analysis{}, err
+	componentOf := map[fileinfo.Declaration]int{}
+	for i, component := range components {
+		for _, decl := range component {
+			componentOf[decl] = i
+		}
+	}
+	referencedByDeadCode := set.NewSet[int]()
+	for decl, peers := range deadGraph {
+		for peer := range peers {
+			if componentOf[decl] != componentOf[peer] {
+				referencedByDeadCode.Add(componentOf[peer])
+			}
+		}
 	}
 
-	referenceGraph, err := references.BuildReferenceGraph(path, fileInfos, walkOptions)
-	if err != nil {
-		return analysis{}, err
+	for i := len(components) - 1; i >= 0; i-- {
+		component := components[i]
+		names, err := analysis.DeclarationNames(component)
+		if err != nil {
+			return err
+		}
+
+		description := "1 declaration"
+		if len(component) > 1 {
+			description = fmt.Sprintf("%d declarations, self-referential", len(component))
+		}
+		if referencedByDeadCode.Contains(i) {
+			description += ", referenced by dead code"
+		} else if len(component) > 1 {
+			description += " island"
+		}
+		fmt.Printf("dead cluster (%s):\n", description)
+		for _, name := range names {
+			fmt.Printf("\t%s\n", name)
+		}
 	}
+	return nil
+}
 
-	unreachable := set.NewSet[fileinfo.Declaration]()
-	entrypoints := set.NewSet[fileinfo.Declaration]()
-	for decl := range referenceGraph {
-		unreachable.Add(decl)
-		if decl.Parent.Entrypoints.Contains(decl.Name) {
-			entrypoints.Add(decl)
+func paramsMain(args paramsArgs) error {
+	for _, path := range args.Paths {
+		path, err := filepath.Abs(path)
+		if err != nil {
+			return err
+		}
+		if err := checkDirectory(path); err != nil {
+			return err
+		}
+
+		analysis, err := project.Analyze(path, args.options())
+		if err != nil {
+			return err
+		}
+		findings, err := analysis.UnusedParams()
+		if err != nil {
+			return err
+		}
+		for _, finding := range findings {
+			name, err := analysis.DeclarationName(finding.Declaration)
+			if err != nil {
+				return err
+			}
+			switch finding.Kind {
+			case params.KindUnusedParam:
+				fmt.Printf("%s: parameter %s is never used\n", name, finding.Name)
+			case params.KindDiscardedResult:
+				fmt.Printf("%s: result %d (%s) is discarded by every caller\n", name, finding.Index, finding.Name)
+			}
 		}
 	}
-	_ = referenceGraph.DFS(entrypoints.ToSlice(), func(node fileinfo.Declaration) error {
-		unreachable.Remove(node)
-		return nil
-	})
-
-	return analysis{
-		fileInfos,
-		referenceGraph,
-		unreachable,
-		entrypoints,
-	}, nil
+	return nil
+}
+
+func methodsMain(args methodsArgs) error {
+	for _, path := range args.Paths {
+		path, err := filepath.Abs(path)
+		if err != nil {
+			return err
+		}
+		if err := checkDirectory(path); err != nil {
+			return err
+		}
+
+		analysis, err := project.Analyze(path, args.options())
+		if err != nil {
+			return err
+		}
+		for _, method := range analysis.InterfaceMethods() {
+			if method.Status == interfaces.StatusReferenced {
+				continue
+			}
+			if args.DeadOnly && method.Status != interfaces.StatusDeadInterface {
+				continue
+			}
+			name, err := analysis.DeclarationName(method.Declaration)
+			if err != nil {
+				return err
+			}
+			line := fmt.Sprintf("%s: %s", name, method.Status)
+			if len(method.Interfaces) > 0 {
+				ifaceNames, err := analysis.DeclarationNames(method.Interfaces)
+				if err != nil {
+					return err
+				}
+				line += fmt.Sprintf(" (%s)", strings.Join(ifaceNames, ", "))
+			}
+			if len(method.Rules) > 0 {
+				line += fmt.Sprintf(" (%s)", strings.Join(method.Rules, ", "))
+			}
+			fmt.Println(line)
+		}
+	}
+	return nil
+}
+
+func impactMain(args impactArgs) error {
+	for _, path := range args.Paths {
+		path, err := filepath.Abs(path)
+		if err != nil {
+			return err
+		}
+		if err := checkDirectory(path); err != nil {
+			return err
+		}
+
+		analysis, err := project.Analyze(path, args.options())
+		if err != nil {
+			return err
+		}
+
+		// Removing a declaration makes everything it dominates unreachable,
+		// so the size of its subtree in the dominator tree is the size of its impact.
+		dominatorTree := analysis.ReferenceGraph.DominatorTree(analysis.Entrypoints.ToSlice())
+		lines := ownLines(analysis.FileInfos)
+		impactLines := map[fileinfo.Declaration]int{}
+		impactDecls := map[fileinfo.Declaration]int{}
+		var measure func(fileinfo.Declaration)
+		measure = func(decl fileinfo.Declaration) {
+			if _, ok := impactLines[decl]; ok {
+				return
+			}
+			impactLines[decl] = lines[decl]
+			impactDecls[decl] = 1
+			for dominated := range dominatorTree[decl] {
+				measure(dominated)
+				impactLines[decl] += impactLines[dominated]
+				impactDecls[decl] += impactDecls[dominated]
+			}
+		}
+
+		decls := make([]fileinfo.Declaration, 0, len(dominatorTree))
+		names := map[fileinfo.Declaration]string{}
+		for decl := range dominatorTree {
+			measure(decl)
+			name, err := analysis.DeclarationName(decl)
+			if err != nil {
+				return err
+			}
+			decls = append(decls, decl)
+			names[decl] = name
+		}
+		sort.Slice(decls, func(i, j int) bool {
+			if impactLines[decls[i]] != impactLines[decls[j]] {
+				return impactLines[decls[i]] > impactLines[decls[j]]
+			}
+			return names[decls[i]] < names[decls[j]]
+		})
+		if args.Limit > 0 && len(decls) > args.Limit {
+			decls = decls[:args.Limit]
+		}
+
+		for _, decl := range decls {
+			fmt.Printf(
+				"%s: %d lines in %d declarations\n",
+				names[decl],
+				impactLines[decl],
+				impactDecls[decl],
+			)
+			if !args.Verbose {
+				continue
+			}
+			dominated := []fileinfo.Declaration{}
+			_ = dominatorTree.DFS([]fileinfo.Declaration{decl}, func(node fileinfo.Declaration) error {
+				if node != decl {
+					dominated = append(dominated, node)
+				}
+				return nil
+			})
+			dominatedNames, err := analysis.DeclarationNames(dominated)
+			if err != nil {
+				return err
+			}
+			for _, name := range dominatedNames {
+				fmt.Printf("\t%s\n", name)
+			}
+		}
+	}
+	return nil
+}
+
+// ownLines counts the lines of each declaration in `fileInfos` which no other declaration spans,
+// so that declarations nested inside of another, like the methods of an interface, aren't counted twice.
+func ownLines(fileInfos map[string]*fileinfo.FileInfo) map[fileinfo.Declaration]int {
+	lines := map[fileinfo.Declaration]int{}
+	for _, fileInfo := range fileInfos {
+		decls := make([]fileinfo.Declaration, 0, len(fileInfo.Declarations))
+		for _, decl := range fileInfo.Declarations {
+			decls = append(decls, decl)
+		}
+		sort.Slice(decls, func(i, j int) bool {
+			if decls[i].Pos.Offset != decls[j].Pos.Offset {
+				return decls[i].Pos.Offset < decls[j].Pos.Offset
+			}
+			if decls[i].End.Offset != decls[j].End.Offset {
+				return decls[i].End.Offset > decls[j].End.Offset
+			}
+			return decls[i].Name < decls[j].Name
+		})
+
+		outerEnd := -1
+		for _, decl := range decls {
+			if decl.End.Offset <= outerEnd {
+				lines[decl] = 0
+				continue
+			}
+			lines[decl] = decl.End.Line - decl.Pos.Line + 1
+			outerEnd = decl.End.Offset
+		}
+	}
+	return lines
 }
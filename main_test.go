@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alecthomas/kong"
+	"github.com/crockeo/schoner/internal/testproject"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// parseArgs parses `argv` as the command line would be.
+func parseArgs(t *testing.T, argv ...string) args {
+	t.Helper()
+	parsed := args{}
+	parser, err := kong.New(&parsed)
+	require.NoError(t, err)
+	_, err = parser.Parse(argv)
+	require.NoError(t, err)
+	return parsed
+}
+
+const chainContents string = `package main
+
+func main() { first() }
+
+func first() { second() }
+
+func second() { third() }
+
+func third() {}
+`
+
+func TestVisualizeFocus(t *testing.T) {
+	tests := map[string]struct {
+		flags    []string
+		expected []string
+	}{
+		"unfocused":     {expected: []string{"main", "first", "second", "third"}},
+		"both":          {flags: []string{"--focus", "second", "--depth", "1"}, expected: []string{"first", "second", "third"}},
+		"out":           {flags: []string{"--focus", "first", "--direction", "out"}, expected: []string{"first", "second", "third"}},
+		"in":            {flags: []string{"--focus", "second", "--direction", "in", "--depth", "1"}, expected: []string{"first", "second"}},
+		"qualified":     {flags: []string{"--focus", "main.go::third", "--direction", "in", "--depth", "3"}, expected: []string{"main", "first", "second", "third"}},
+		"default depth": {flags: []string{"--focus", "main", "--direction", "out"}, expected: []string{"main", "first", "second"}},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			root := testproject.Write(t, map[string]string{"main.go": chainContents})
+			outputDir := t.TempDir()
+			argv := append([]string{"visualize", "--format", "json", "--output-dir", outputDir}, test.flags...)
+			parsed := parseArgs(t, append(argv, root)...)
+			require.NoError(t, visualizeMain(parsed.Visualize))
+
+			contents, err := os.ReadFile(filepath.Join(outputDir, filepath.Base(root)+".json"))
+			require.NoError(t, err)
+			var output struct {
+				Nodes []struct {
+					Name string `json:"name"`
+				} `json:"nodes"`
+			}
+			require.NoError(t, json.Unmarshal(contents, &output))
+			names := []string{}
+			for _, node := range output.Nodes {
+				names = append(names, node.Name)
+			}
+			assert.ElementsMatch(t, test.expected, names)
+		})
+	}
+}
+
+func TestVisualizeFocusUnknown(t *testing.T) {
+	root := testproject.Write(t, map[string]string{"main.go": chainContents})
+	parsed := parseArgs(t, "visualize", "--format", "json", "--output-dir", t.TempDir(), "--focus", "missing", root)
+	assert.Error(t, visualizeMain(parsed.Visualize))
+}
+
+func TestCommandsRequireDirectories(t *testing.T) {
+	root := testproject.Write(t, map[string]string{"main.go": chainContents})
+	path := filepath.Join(root, "main.go")
+	commands := map[string]func(t *testing.T) error{
+		"visualize": func(t *testing.T) error {
+			return visualizeMain(parseArgs(t, "visualize", "--output-dir", "-", path).Visualize)
+		},
+		"unreachable": func(t *testing.T) error {
+			return unreachableMain(parseArgs(t, "unreachable", path).Unreachable)
+		},
+		"impact": func(t *testing.T) error {
+			return impactMain(parseArgs(t, "impact", path).Impact)
+		},
+		"params": func(t *testing.T) error {
+			return paramsMain(parseArgs(t, "params", path).Params)
+		},
+		"methods": func(t *testing.T) error {
+			return methodsMain(parseArgs(t, "methods", path).Methods)
+		},
+	}
+	for name, command := range commands {
+		t.Run(name, func(t *testing.T) {
+			assert.ErrorContains(t, command(t), "is not a directory")
+		})
+	}
+}
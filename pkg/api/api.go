@@ -0,0 +1,399 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/crockeo/schoner/pkg/phases/fileinfo"
+	"github.com/crockeo/schoner/pkg/project"
+	"github.com/crockeo/schoner/pkg/visualize"
+)
+
+// Server answers reachability queries about a single project over HTTP.
+//
+// The project is analyzed once up front,
+// and then again whenever Watch notices that one of its files has changed,
+// so that queries never have to wait on parsing the project.
+type Server struct {
+	root    string
+	options []project.Option
+
+	mu          sync.RWMutex
+	analysis    *project.Analysis
+	analyzedAt  time.Time
+	fingerprint uint64
+	lastErr     error
+}
+
+// NewServer analyzes the project at `root`, which must be an absolute path.
+func NewServer(root string, options ...project.Option) (*Server, error) {
+	s := &Server{root: root, options: options}
+	if err := s.Reanalyze(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Reanalyze analyzes the project again.
+// If analysis fails the previous analysis keeps being served,
+// and the error is reported by the /status endpoint.
+func (s *Server) Reanalyze() error {
+	fingerprint, err := project.Fingerprint(s.root, s.options...)
+	if err != nil {
+		return s.setError(err)
+	}
+	analysis, err := project.Analyze(s.root, s.options...)
+	if err != nil {
+		return s.setError(err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.analysis = analysis
+	s.analyzedAt = time.Now()
+	s.fingerprint = fingerprint
+	s.lastErr = nil
+	return nil
+}
+
+func (s *Server) setError(err error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastErr = err
+	return err
+}
+
+// Watch checks whether any of the project's files have changed every `interval`,
+// and re-analyzes the project when they have, until `ctx` is done.
+func (s *Server) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		fingerprint, err := project.Fingerprint(s.root, s.options...)
+		if err != nil {
+			s.setError(err)
+			continue
+		}
+		s.mu.RLock()
+		changed := fingerprint != s.fingerprint
+		s.mu.RUnlock()
+		if changed {
+			_ = s.Reanalyze()
+		}
+	}
+}
+
+// Handler returns the handler which serves every endpoint:
+//
+//   - /status reports when the project was last analyzed, and whether re-analyzing it failed.
+//   - /unreachable lists every unreachable declaration.
+//   - /refs?symbol= lists the references to and from each declaration matching the symbol.
+//   - /why?symbol= explains why each declaration matching the symbol is or isn't reachable.
+//   - /graph?focus=&depth=&direction= returns the reference graph,
+//     optionally limited to the declarations near a symbol, in the same format as `visualize --format json`.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", s.handleStatus)
+	mux.HandleFunc("/unreachable", s.withAnalysis(s.handleUnreachable))
+	mux.HandleFunc("/refs", s.withAnalysis(s.handleRefs))
+	mux.HandleFunc("/why", s.withAnalysis(s.handleWhy))
+	mux.HandleFunc("/graph", s.withAnalysis(s.handleGraph))
+	return mux
+}
+
+type declaration struct {
+	Name    string `json:"name"`
+	Symbol  string `json:"symbol"`
+	Kind    string `json:"kind"`
+	Package string `json:"package"`
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+}
+
+type reference struct {
+	Declaration declaration `json:"declaration"`
+	Kind        string      `json:"kind"`
+	File        string      `json:"file"`
+	Line        int         `json:"line"`
+	Column      int         `json:"column"`
+}
+
+type statusResponse struct {
+	Root       string    `json:"root"`
+	AnalyzedAt time.Time `json:"analyzedAt"`
+	Error      string    `json:"error,omitempty"`
+}
+
+type unreachableResponse struct {
+	Declarations []declaration `json:"declarations"`
+}
+
+type refsResponse struct {
+	Declaration  declaration `json:"declaration"`
+	ReferencedBy []reference `json:"referencedBy"`
+	References   []reference `json:"references"`
+}
+
+type whyResponse struct {
+	Declaration declaration   `json:"declaration"`
+	Reachable   bool          `json:"reachable"`
+	Entrypoint  bool          `json:"entrypoint"`
+	Path        []declaration `json:"path"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+type analysisHandler func(w http.ResponseWriter, r *http.Request, analysis *project.Analysis) error
+
+// httpError is an error which should be reported with a particular status code.
+type httpError struct {
+	status int
+	err    error
+}
+
+func (e httpError) Error() string {
+	return e.err.Error()
+}
+
+func (e httpError) Unwrap() error {
+	return e.err
+}
+
+func badRequest(format string, args ...any) error {
+	return httpError{status: http.StatusBadRequest, err: fmt.Errorf(format, args...)}
+}
+
+// withAnalysis passes the latest analysis to `handler`,
+// and reports any error it returns as JSON.
+func (s *Server) withAnalysis(handler analysisHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeError(w, httpError{status: http.StatusMethodNotAllowed, err: fmt.Errorf("method %s is not allowed", r.Method)})
+			return
+		}
+		s.mu.RLock()
+		analysis := s.analysis
+		s.mu.RUnlock()
+		if err := handler(w, r, analysis); err != nil {
+			writeError(w, err)
+		}
+	}
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	response := statusResponse{Root: s.root, AnalyzedAt: s.analyzedAt}
+	if s.lastErr != nil {
+		response.Error = s.lastErr.Error()
+	}
+	s.mu.RUnlock()
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s *Server) handleUnreachable(w http.ResponseWriter, r *http.Request, analysis *project.Analysis) error {
+	response := unreachableResponse{Declarations: []declaration{}}
+	for _, decl := range analysis.Unreachable.Sorted(fileinfo.Declaration.Less) {
+		converted, err := convertDeclaration(analysis, decl)
+		if err != nil {
+			return err
+		}
+		response.Declarations = append(response.Declarations, converted)
+	}
+	writeJSON(w, http.StatusOK, response)
+	return nil
+}
+
+func (s *Server) handleRefs(w http.ResponseWriter, r *http.Request, analysis *project.Analysis) error {
+	decls, err := findDeclarations(r, "symbol", analysis)
+	if err != nil {
+		return err
+	}
+
+	response := []refsResponse{}
+	for _, decl := range decls {
+		converted, err := convertDeclaration(analysis, decl)
+		if err != nil {
+			return err
+		}
+		refs := refsResponse{Declaration: converted, ReferencedBy: []reference{}, References: []reference{}}
+		for _, from := range analysis.Referrers.SortedChildren(decl, fileinfo.Declaration.Less) {
+			converted, err := convertReferences(analysis, from, from, decl)
+			if err != nil {
+				return err
+			}
+			refs.ReferencedBy = append(refs.ReferencedBy, converted...)
+		}
+		for _, to := range analysis.ReferenceGraph.SortedChildren(decl, fileinfo.Declaration.Less) {
+			converted, err := convertReferences(analysis, to, decl, to)
+			if err != nil {
+				return err
+			}
+			refs.References = append(refs.References, converted...)
+		}
+		response = append(response, refs)
+	}
+	writeJSON(w, http.StatusOK, response)
+	return nil
+}
+
+func (s *Server) handleWhy(w http.ResponseWriter, r *http.Request, analysis *project.Analysis) error {
+	decls, err := findDeclarations(r, "symbol", analysis)
+	if err != nil {
+		return err
+	}
+
+	response := []whyResponse{}
+	for _, decl := range decls {
+		converted, err := convertDeclaration(analysis, decl)
+		if err != nil {
+			return err
+		}
+		why := whyResponse{
+			Declaration: converted,
+			Reachable:   !analysis.Unreachable.Contains(decl),
+			Entrypoint:  analysis.Entrypoints.Contains(decl),
+			Path:        []declaration{},
+		}
+		for _, step := range analysis.ReachabilityPath(decl) {
+			converted, err := convertDeclaration(analysis, step)
+			if err != nil {
+				return err
+			}
+			why.Path = append(why.Path, converted)
+		}
+		response = append(response, why)
+	}
+	writeJSON(w, http.StatusOK, response)
+	return nil
+}
+
+func (s *Server) handleGraph(w http.ResponseWriter, r *http.Request, analysis *project.Analysis) error {
+	referenceGraph := analysis.ReferenceGraph
+	if focus := r.URL.Query().Get("focus"); focus != "" {
+		depth := 2
+		if rawDepth := r.URL.Query().Get("depth"); rawDepth != "" {
+			var err error
+			depth, err = strconv.Atoi(rawDepth)
+			if err != nil || depth < 0 {
+				return badRequest("depth must be a non-negative integer, got `%s`", rawDepth)
+			}
+		}
+		direction := project.Direction(r.URL.Query().Get("direction"))
+		switch direction {
+		case "":
+			direction = project.DirectionBoth
+		case project.DirectionIn, project.DirectionOut, project.DirectionBoth:
+		default:
+			return badRequest("direction must be one of in, out, or both, got `%s`", direction)
+		}
+
+		var err error
+		referenceGraph, err = analysis.Focus(focus, depth, direction)
+		if err != nil {
+			return notFound(err)
+		}
+	}
+
+	// Export into a buffer first, so that a failed export can still be reported as an error.
+	body := &bytes.Buffer{}
+	err := visualize.Export(body, visualize.FormatJSON, referenceGraph, analysis.Entrypoints, analysis.Unreachable)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(body.Bytes())
+	return err
+}
+
+// findDeclarations finds the declarations matching the symbol in the query parameter `param`.
+func findDeclarations(r *http.Request, param string, analysis *project.Analysis) ([]fileinfo.Declaration, error) {
+	symbol := r.URL.Query().Get(param)
+	if symbol == "" {
+		return nil, badRequest("missing required query parameter `%s`", param)
+	}
+	decls, err := analysis.FindDeclarations(symbol)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return decls, nil
+}
+
+func notFound(err error) error {
+	if errors.Is(err, project.ErrNoMatchingDeclaration) {
+		return httpError{status: http.StatusNotFound, err: err}
+	}
+	return err
+}
+
+func convertDeclaration(analysis *project.Analysis, decl fileinfo.Declaration) (declaration, error) {
+	symbol, err := analysis.DeclarationName(decl)
+	if err != nil {
+		return declaration{}, err
+	}
+	file, err := analysis.RelativePath(decl.Parent.Filename)
+	if err != nil {
+		return declaration{}, err
+	}
+	return declaration{
+		Name:    decl.Name,
+		Symbol:  symbol,
+		Kind:    decl.Kind.String(),
+		Package: decl.Parent.ImportPath,
+		File:    file,
+		Line:    decl.Pos.Line,
+	}, nil
+}
+
+// convertReferences converts every reference from `from` to `to`,
+// describing each of them by the declaration `peer`.
+func convertReferences(analysis *project.Analysis, peer fileinfo.Declaration, from fileinfo.Declaration, to fileinfo.Declaration) ([]reference, error) {
+	converted, err := convertDeclaration(analysis, peer)
+	if err != nil {
+		return nil, err
+	}
+	refs := []reference{}
+	for _, ref := range analysis.ReferenceGraph.EdgeReferences(from, to) {
+		file, err := analysis.RelativePath(ref.Pos.Filename)
+		if err != nil {
+			return nil, err
+		}
+		refs = append(refs, reference{
+			Declaration: converted,
+			Kind:        ref.Kind.String(),
+			File:        file,
+			Line:        ref.Pos.Line,
+			Column:      ref.Pos.Column,
+		})
+	}
+	return refs, nil
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	var statusErr httpError
+	if errors.As(err, &statusErr) {
+		status = statusErr.status
+	}
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(value)
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/crockeo/schoner/internal/testproject"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const mainContents string = `package main
+
+func main() {
+	live()
+}
+
+func live() {}
+
+func dead() {
+	live()
+}
+`
+
+func newTestServer(t *testing.T) (*Server, *httptest.Server) {
+	root := testproject.Write(t, map[string]string{"main.go": mainContents})
+	server, err := NewServer(root)
+	require.NoError(t, err)
+	httpServer := httptest.NewServer(server.Handler())
+	t.Cleanup(httpServer.Close)
+	return server, httpServer
+}
+
+func get(t *testing.T, httpServer *httptest.Server, path string, response any) int {
+	resp, err := http.Get(httpServer.URL + path)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(response))
+	return resp.StatusCode
+}
+
+func TestUnreachable(t *testing.T) {
+	_, httpServer := newTestServer(t)
+
+	response := unreachableResponse{}
+	assert.Equal(t, http.StatusOK, get(t, httpServer, "/unreachable", &response))
+	require.Len(t, response.Declarations, 1)
+	assert.Equal(t, "main.go::dead", response.Declarations[0].Symbol)
+	assert.Equal(t, "example.com/root", response.Declarations[0].Package)
+}
+
+func TestRefs(t *testing.T) {
+	_, httpServer := newTestServer(t)
+
+	response := []refsResponse{}
+	assert.Equal(t, http.StatusOK, get(t, httpServer, "/refs?symbol=live", &response))
+	require.Len(t, response, 1)
+	referencedBy := []string{}
+	for _, ref := range response[0].ReferencedBy {
+		referencedBy = append(referencedBy, ref.Declaration.Name)
+		assert.Equal(t, "call", ref.Kind)
+	}
+	assert.Equal(t, []string{"main", "dead"}, referencedBy)
+	assert.Empty(t, response[0].References)
+
+	errResponse := errorResponse{}
+	assert.Equal(t, http.StatusNotFound, get(t, httpServer, "/refs?symbol=missing", &errResponse))
+	assert.Equal(t, "no declaration matches symbol `missing`", errResponse.Error)
+	assert.Equal(t, http.StatusBadRequest, get(t, httpServer, "/refs", &errResponse))
+}
+
+func TestWhy(t *testing.T) {
+	_, httpServer := newTestServer(t)
+
+	response := []whyResponse{}
+	assert.Equal(t, http.StatusOK, get(t, httpServer, "/why?symbol=live", &response))
+	require.Len(t, response, 1)
+	assert.True(t, response[0].Reachable)
+	path := []string{}
+	for _, step := range response[0].Path {
+		path = append(path, step.Name)
+	}
+	assert.Equal(t, []string{"main", "live"}, path)
+
+	assert.Equal(t, http.StatusOK, get(t, httpServer, "/why?symbol=dead", &response))
+	require.Len(t, response, 1)
+	assert.False(t, response[0].Reachable)
+	assert.Empty(t, response[0].Path)
+}
+
+func TestGraph(t *testing.T) {
+	_, httpServer := newTestServer(t)
+
+	response := struct {
+		Nodes []struct {
+			Name string `json:"name"`
+		} `json:"nodes"`
+		Edges []struct{} `json:"edges"`
+	}{}
+	assert.Equal(t, http.StatusOK, get(t, httpServer, "/graph", &response))
+	assert.Len(t, response.Nodes, 3)
+	assert.Len(t, response.Edges, 2)
+
+	assert.Equal(t, http.StatusOK, get(t, httpServer, "/graph?focus=dead&direction=in", &response))
+	require.Len(t, response.Nodes, 1)
+	assert.Equal(t, "dead", response.Nodes[0].Name)
+
+	errResponse := errorResponse{}
+	assert.Equal(t, http.StatusBadRequest, get(t, httpServer, "/graph?focus=dead&depth=-1", &errResponse))
+}
+
+func TestReanalyze(t *testing.T) {
+	server, httpServer := newTestServer(t)
+
+	contents := mainContents + "\nfunc alsoDead() {}\n"
+	require.NoError(t, os.WriteFile(filepath.Join(server.root, "main.go"), []byte(contents), 0o644))
+	require.NoError(t, server.Reanalyze())
+
+	response := unreachableResponse{}
+	assert.Equal(t, http.StatusOK, get(t, httpServer, "/unreachable", &response))
+	assert.Len(t, response.Declarations, 2)
+
+	// A project which fails to analyze keeps serving its last analysis.
+	require.NoError(t, os.WriteFile(filepath.Join(server.root, "main.go"), []byte("package main\nfunc {"), 0o644))
+	assert.Error(t, server.Reanalyze())
+	assert.Equal(t, http.StatusOK, get(t, httpServer, "/unreachable", &response))
+	assert.Len(t, response.Declarations, 2)
+
+	status := statusResponse{}
+	assert.Equal(t, http.StatusOK, get(t, httpServer, "/status", &status))
+	assert.NotEmpty(t, status.Error)
+}
@@ -24,8 +24,12 @@ func ExprName(expr ast.Expr) (string, bool) {
 		return expr.Name, true
 	case *ast.StarExpr:
 		return ExprName(expr.X)
+	case *ast.ParenExpr:
+		return ExprName(expr.X)
 	case *ast.IndexExpr:
 		return ExprName(expr.X)
+	case *ast.IndexListExpr:
+		return ExprName(expr.X)
 	default:
 		return "", false
 	}
@@ -1,17 +1,15 @@
 package astutil
 
 import (
-	"errors"
 	"fmt"
 	"go/ast"
 )
 
-var ErrAmbiguousOuterDecl = errors.New("ambiguous outer declaration name")
-
-func Walk(fileAst *ast.File, fn func([]ast.Node, ast.Node) error) error {
+// Walk calls `fn` with every node beneath `root`, along with the path of nodes from `root` to it.
+func Walk(root ast.Node, fn func([]ast.Node, ast.Node) error) error {
 	path := []ast.Node{}
 	var err error
-	ast.Inspect(fileAst, func(node ast.Node) bool {
+	ast.Inspect(root, func(node ast.Node) bool {
 		if err != nil {
 			return false
 		}
@@ -26,19 +24,43 @@ func Walk(fileAst *ast.File, fn func([]ast.Node, ast.Node) error) error {
 	return err
 }
 
-func OuterDeclName(path []ast.Node) (string, error) {
-	for _, node := range path {
-		switch node := node.(type) {
+// OuterDeclNames finds the names of the top-level declarations which `node`, found beneath `path`, belongs to.
+//
+// Within a value spec which declares several names, a node belongs to the name whose value contains it,
+// or to every name when it's part of the spec's type or of a single value shared by every name, e.g. `var a, b = f()`.
+func OuterDeclNames(path []ast.Node, node ast.Node) ([]string, error) {
+	for i, current := range path {
+		switch current := current.(type) {
 		case *ast.FuncDecl:
-			return FunctionName(node)
+			name, err := FunctionName(current)
+			if err != nil {
+				return nil, err
+			}
+			return []string{name}, nil
 		case *ast.TypeSpec:
-			return node.Name.Name, nil
+			return []string{current.Name.Name}, nil
 		case *ast.ValueSpec:
-			if len(node.Names) != 1 {
-				return "", fmt.Errorf("ValueSpec contains more than 1 name: %w", ErrAmbiguousOuterDecl)
+			child := node
+			if i+1 < len(path) {
+				child = path[i+1]
 			}
-			return node.Names[0].Name, nil
+			return valueSpecNames(current, child), nil
+		}
+	}
+	return nil, fmt.Errorf("no decl")
+}
+
+// valueSpecNames finds the names in `spec` which its direct child `child` belongs to.
+func valueSpecNames(spec *ast.ValueSpec, child ast.Node) []string {
+	names := []string{}
+	for i, name := range spec.Names {
+		if child == name {
+			return []string{name.Name}
+		}
+		if len(spec.Values) == len(spec.Names) && child == spec.Values[i] {
+			return []string{name.Name}
 		}
+		names = append(names, name.Name)
 	}
-	return "", fmt.Errorf("no decl")
+	return names
 }
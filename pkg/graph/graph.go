@@ -1,9 +1,19 @@
 package graph
 
-import "github.com/crockeo/schoner/pkg/set"
+import (
+	"sort"
+
+	"github.com/crockeo/schoner/pkg/set"
+)
 
 type Graph[T comparable] map[T]set.Set[T]
 
+// Edge identifies a single directed edge within a Graph.
+type Edge[T comparable] struct {
+	From T
+	To   T
+}
+
 func NewGraph[T comparable]() Graph[T] {
 	return map[T]set.Set[T]{}
 }
@@ -36,10 +46,37 @@ func (g Graph[T]) AddEdge(from T, to T) bool {
 	return true
 }
 
+// SortedNodes returns every node in the graph in the order given by `less`.
+func (g Graph[T]) SortedNodes(less func(T, T) bool) []T {
+	nodes := make([]T, 0, len(g))
+	for node := range g {
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return less(nodes[i], nodes[j])
+	})
+	return nodes
+}
+
+// SortedChildren returns the children of `node` in the order given by `less`.
+func (g Graph[T]) SortedChildren(node T, less func(T, T) bool) []T {
+	return g[node].Sorted(less)
+}
+
 func (g Graph[T]) DFS(roots []T, visitor func(T) error) error {
+	return g.dfs(roots, func(node T) []T { return g[node].ToSlice() }, visitor)
+}
+
+// SortedDFS is like DFS, but visits the children of each node in the order given by `less`,
+// so that the order in which nodes are visited is the same between runs.
+func (g Graph[T]) SortedDFS(roots []T, less func(T, T) bool, visitor func(T) error) error {
+	return g.dfs(roots, func(node T) []T { return g.SortedChildren(node, less) }, visitor)
+}
+
+func (g Graph[T]) dfs(roots []T, children func(T) []T, visitor func(T) error) error {
 	visited := set.NewSet[T]()
 	for _, root := range roots {
-		if visited.Contains(root) {
+		if !visited.Add(root) {
 			continue
 		}
 
@@ -51,14 +88,269 @@ func (g Graph[T]) DFS(roots []T, visitor func(T) error) error {
 			if err := visitor(next); err != nil {
 				return err
 			}
-			for child := range g[next] {
-				if visited.Contains(child) {
+			// Children are pushed in reverse so that they're popped in order.
+			nextChildren := children(next)
+			for i := len(nextChildren) - 1; i >= 0; i-- {
+				child := nextChildren[i]
+				if !visited.Add(child) {
 					continue
 				}
 				stack = append(stack, child)
-				visited.Add(child)
 			}
 		}
 	}
 	return nil
 }
+
+// Reverse returns a copy of the graph in which every edge points in the opposite direction.
+func (g Graph[T]) Reverse() Graph[T] {
+	reversed := NewGraph[T]()
+	for node, children := range g {
+		reversed.AddNode(node)
+		for child := range children {
+			reversed.AddEdge(child, node)
+		}
+	}
+	return reversed
+}
+
+// WithinDistance returns every node which can be reached from `roots`
+// by following at most `depth` edges, including the roots themselves.
+func (g Graph[T]) WithinDistance(roots []T, depth int) set.Set[T] {
+	visited := set.NewSet[T]()
+	frontier := []T{}
+	for _, root := range roots {
+		if g.ContainsNode(root) && visited.Add(root) {
+			frontier = append(frontier, root)
+		}
+	}
+	for distance := 0; distance < depth && len(frontier) > 0; distance++ {
+		nextFrontier := []T{}
+		for _, node := range frontier {
+			for child := range g[node] {
+				if visited.Add(child) {
+					nextFrontier = append(nextFrontier, child)
+				}
+			}
+		}
+		frontier = nextFrontier
+	}
+	return visited
+}
+
+// ShortestPath returns the shortest path from any of `roots` to `target`,
+// including both ends, or nil if `target` can't be reached.
+// Children are visited in the order given by `less`, so the result is the same between runs.
+func (g Graph[T]) ShortestPath(roots []T, target T, less func(T, T) bool) []T {
+	parents := map[T]T{}
+	visited := set.NewSet[T]()
+	frontier := []T{}
+	for _, root := range roots {
+		if g.ContainsNode(root) && visited.Add(root) {
+			frontier = append(frontier, root)
+		}
+	}
+	for len(frontier) > 0 && !visited.Contains(target) {
+		nextFrontier := []T{}
+		for _, node := range frontier {
+			for _, child := range g.SortedChildren(node, less) {
+				if visited.Add(child) {
+					parents[child] = node
+					nextFrontier = append(nextFrontier, child)
+				}
+			}
+		}
+		frontier = nextFrontier
+	}
+	if !visited.Contains(target) {
+		return nil
+	}
+
+	path := []T{target}
+	for {
+		parent, ok := parents[path[len(path)-1]]
+		if !ok {
+			break
+		}
+		path = append(path, parent)
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+// Subgraph returns the subgraph induced by `nodes`,
+// i.e. those nodes and every edge between them.
+func (g Graph[T]) Subgraph(nodes set.Set[T]) Graph[T] {
+	subgraph := NewGraph[T]()
+	for node := range nodes {
+		if !g.ContainsNode(node) {
+			continue
+		}
+		subgraph.AddNode(node)
+		for child := range g[node] {
+			if nodes.Contains(child) {
+				subgraph.AddEdge(node, child)
+			}
+		}
+	}
+	return subgraph
+}
+
+// StronglyConnectedComponents partitions the graph into its strongly connected components
+// using Tarjan's algorithm.
+//
+// Components are returned in reverse topological order:
+// every component appears before any of the components which reference it.
+// Nodes are visited in the order given by `less`, so the result is the same between runs.
+func (g Graph[T]) StronglyConnectedComponents(less func(T, T) bool) [][]T {
+	type nodeState struct {
+		index   int
+		lowLink int
+		onStack bool
+	}
+
+	states := map[T]*nodeState{}
+	stack := []T{}
+	components := [][]T{}
+
+	var strongConnect func(T)
+	strongConnect = func(node T) {
+		state := &nodeState{index: len(states), lowLink: len(states), onStack: true}
+		states[node] = state
+		stack = append(stack, node)
+
+		for _, child := range g.SortedChildren(node, less) {
+			childState, visited := states[child]
+			if !visited {
+				strongConnect(child)
+				childState = states[child]
+				if childState.lowLink < state.lowLink {
+					state.lowLink = childState.lowLink
+				}
+			} else if childState.onStack && childState.index < state.lowLink {
+				state.lowLink = childState.index
+			}
+		}
+
+		if state.lowLink != state.index {
+			return
+		}
+		component := []T{}
+		for {
+			member := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			states[member].onStack = false
+			component = append(component, member)
+			if member == node {
+				break
+			}
+		}
+		components = append(components, component)
+	}
+
+	for _, node := range g.SortedNodes(less) {
+		if _, visited := states[node]; !visited {
+			strongConnect(node)
+		}
+	}
+	return components
+}
+
+// DominatorTree computes the dominator tree of every node reachable from `roots`,
+// as though `roots` were all children of a single virtual root node.
+// Each edge in the returned graph points from a node to the nodes it immediately dominates,
+// such that removing a node from this graph makes every node in its subtree unreachable.
+//
+// The virtual root is not included in the tree,
+// so the nodes which it immediately dominates have no parent.
+//
+// This uses the iterative algorithm described in
+// "A Simple, Fast Dominance Algorithm" by Cooper, Harvey, and Kennedy.
+func (g Graph[T]) DominatorTree(roots []T) Graph[T] {
+	// Nodes are numbered by their position in a postorder traversal from the virtual root,
+	// which is numbered last.
+	order := []T{}
+	index := map[T]int{}
+	visited := set.NewSet[T]()
+	var visit func(T)
+	visit = func(node T) {
+		visited.Add(node)
+		for child := range g[node] {
+			if !visited.Contains(child) {
+				visit(child)
+			}
+		}
+		index[node] = len(order)
+		order = append(order, node)
+	}
+	for _, root := range roots {
+		if g.ContainsNode(root) && !visited.Contains(root) {
+			visit(root)
+		}
+	}
+	virtualRoot := len(order)
+
+	predecessors := make([][]int, len(order))
+	for _, node := range order {
+		for child := range g[node] {
+			predecessors[index[child]] = append(predecessors[index[child]], index[node])
+		}
+	}
+	for _, root := range roots {
+		if i, ok := index[root]; ok {
+			predecessors[i] = append(predecessors[i], virtualRoot)
+		}
+	}
+
+	const undefined = -1
+	idoms := make([]int, len(order)+1)
+	for i := range idoms {
+		idoms[i] = undefined
+	}
+	idoms[virtualRoot] = virtualRoot
+
+	intersect := func(a int, b int) int {
+		for a != b {
+			for a < b {
+				a = idoms[a]
+			}
+			for b < a {
+				b = idoms[b]
+			}
+		}
+		return a
+	}
+
+	for changed := true; changed; {
+		changed = false
+		// Reverse postorder, skipping the virtual root.
+		for i := virtualRoot - 1; i >= 0; i-- {
+			newIdom := undefined
+			for _, predecessor := range predecessors[i] {
+				if idoms[predecessor] == undefined {
+					continue
+				}
+				if newIdom == undefined {
+					newIdom = predecessor
+				} else {
+					newIdom = intersect(predecessor, newIdom)
+				}
+			}
+			if idoms[i] != newIdom {
+				idoms[i] = newIdom
+				changed = true
+			}
+		}
+	}
+
+	tree := NewGraph[T]()
+	for i, node := range order {
+		tree.AddNode(node)
+		if idoms[i] != virtualRoot {
+			tree.AddEdge(order[idoms[i]], node)
+		}
+	}
+	return tree
+}
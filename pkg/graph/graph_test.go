@@ -61,3 +61,131 @@ func TestGraph_DFS(t *testing.T) {
 		visited,
 	)
 }
+
+func TestGraph_Subgraph(t *testing.T) {
+	graph := NewGraph[string]()
+	graph.AddEdge("a", "b")
+	graph.AddEdge("b", "c")
+	graph.AddEdge("c", "a")
+
+	subgraph := graph.Subgraph(set.NewSet("a", "b", "d"))
+	assert.True(t, subgraph.ContainsEdge("a", "b"))
+	assert.False(t, subgraph.ContainsNode("c"))
+	assert.False(t, subgraph.ContainsNode("d"))
+}
+
+func TestGraph_StronglyConnectedComponents(t *testing.T) {
+	graph := NewGraph[string]()
+	graph.AddEdge("a", "b")
+	graph.AddEdge("b", "a")
+	graph.AddEdge("b", "c")
+	graph.AddEdge("c", "d")
+	graph.AddEdge("d", "c")
+	graph.AddNode("e")
+
+	components := graph.StronglyConnectedComponents(func(a, b string) bool { return a < b })
+	assert.Equal(
+		t,
+		[][]string{{"d", "c"}, {"b", "a"}, {"e"}},
+		components,
+	)
+
+	position := map[string]int{}
+	for i, component := range components {
+		for _, node := range component {
+			position[node] = i
+		}
+	}
+	assert.Less(t, position["c"], position["a"])
+}
+
+func TestGraph_DominatorTree(t *testing.T) {
+	graph := NewGraph[string]()
+	graph.AddEdge("a", "b")
+	graph.AddEdge("b", "c")
+	graph.AddEdge("b", "d")
+	graph.AddEdge("c", "e")
+	graph.AddEdge("d", "e")
+	graph.AddEdge("e", "b")
+	graph.AddEdge("f", "d")
+	graph.AddEdge("g", "h")
+	graph.AddEdge("a", "i")
+	graph.AddEdge("i", "j")
+
+	tree := graph.DominatorTree([]string{"a", "f"})
+
+	expected := NewGraph[string]()
+	expected.AddNode("a")
+	expected.AddNode("f")
+	expected.AddNode("b")
+	expected.AddNode("d")
+	expected.AddNode("e")
+	expected.AddEdge("b", "c")
+	expected.AddEdge("a", "i")
+	expected.AddEdge("i", "j")
+	assert.Equal(t, expected, tree)
+}
+
+func TestGraph_SortedNodes(t *testing.T) {
+	graph := NewGraph[string]()
+	graph.AddEdge("b", "c")
+	graph.AddEdge("b", "a")
+	assert.Equal(t, []string{"a", "b", "c"}, graph.SortedNodes(func(a, b string) bool { return a < b }))
+	assert.Equal(t, []string{"c", "a"}, graph.SortedChildren("b", func(a, b string) bool { return a > b }))
+}
+
+func TestGraph_SortedDFS(t *testing.T) {
+	graph := NewGraph[string]()
+	graph.AddEdge("a", "c")
+	graph.AddEdge("a", "b")
+	graph.AddEdge("b", "d")
+	graph.AddEdge("d", "a")
+
+	visited := []string{}
+	err := graph.SortedDFS([]string{"a"}, func(a, b string) bool { return a < b }, func(node string) error {
+		visited = append(visited, node)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "d", "c"}, visited)
+}
+
+func TestGraph_Reverse(t *testing.T) {
+	graph := NewGraph[string]()
+	graph.AddEdge("a", "b")
+	graph.AddNode("c")
+
+	reversed := graph.Reverse()
+	assert.True(t, reversed.ContainsEdge("b", "a"))
+	assert.False(t, reversed.ContainsEdge("a", "b"))
+	assert.True(t, reversed.ContainsNode("c"))
+}
+
+func TestGraph_WithinDistance(t *testing.T) {
+	graph := NewGraph[string]()
+	graph.AddEdge("a", "b")
+	graph.AddEdge("b", "c")
+	graph.AddEdge("c", "d")
+	graph.AddEdge("e", "a")
+
+	assert.Equal(t, set.NewSet("a"), graph.WithinDistance([]string{"a"}, 0))
+	assert.Equal(t, set.NewSet("a", "b", "c"), graph.WithinDistance([]string{"a"}, 2))
+	assert.Equal(t, set.NewSet("a", "b", "c", "d"), graph.WithinDistance([]string{"a"}, 10))
+	assert.Equal(t, set.NewSet[string](), graph.WithinDistance([]string{"z"}, 1))
+}
+
+func TestGraph_ShortestPath(t *testing.T) {
+	graph := NewGraph[string]()
+	graph.AddEdge("a", "b")
+	graph.AddEdge("b", "c")
+	graph.AddEdge("c", "d")
+	graph.AddEdge("a", "d")
+	graph.AddEdge("e", "a")
+	less := func(a, b string) bool { return a < b }
+
+	assert.Equal(t, []string{"a"}, graph.ShortestPath([]string{"a"}, "a", less))
+	assert.Equal(t, []string{"a", "b", "c"}, graph.ShortestPath([]string{"a"}, "c", less))
+	assert.Equal(t, []string{"a", "d"}, graph.ShortestPath([]string{"a"}, "d", less))
+	assert.Equal(t, []string{"e", "a", "b"}, graph.ShortestPath([]string{"c", "e"}, "b", less))
+	assert.Nil(t, graph.ShortestPath([]string{"b"}, "e", less))
+}
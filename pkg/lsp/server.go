@@ -0,0 +1,497 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/crockeo/schoner/pkg/phases/fileinfo"
+	"github.com/crockeo/schoner/pkg/project"
+	"github.com/crockeo/schoner/pkg/walk"
+)
+
+// CommandShowReachabilityPath reports the chain of references which keeps a declaration alive.
+// It takes a document URI and a position within the declaration.
+const CommandShowReachabilityPath = "schoner.showReachabilityPath"
+
+var errExit = errors.New("exit")
+
+// Server is a language server which reports unreachable declarations as diagnostics.
+//
+// It keeps the analysis of the workspace in memory,
+// and re-analyzes it with the contents of any unsaved documents whenever they change.
+type Server struct {
+	reader *bufio.Reader
+	writer io.Writer
+
+	root     string
+	overlay  map[string][]byte
+	analysis *project.Analysis
+	// stale is set when the latest analysis failed,
+	// e.g. because a document doesn't parse while it's being edited,
+	// in which case `analysis` no longer matches the documents and must not be used to edit them.
+	stale     bool
+	published map[string][]byte
+}
+
+func NewServer(r io.Reader, w io.Writer) *Server {
+	return &Server{
+		reader:    bufio.NewReader(r),
+		writer:    w,
+		overlay:   map[string][]byte{},
+		published: map[string][]byte{},
+	}
+}
+
+// Serve handles messages until the client sends `exit` or closes the connection.
+func (s *Server) Serve() error {
+	for {
+		msg, err := readMessage(s.reader)
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if msg.Method == "" {
+			// We never send requests, so there are no responses to handle.
+			continue
+		}
+
+		result, err := s.handle(msg.Method, msg.Params)
+		if errors.Is(err, errExit) {
+			return nil
+		}
+		if msg.ID == nil {
+			if err != nil {
+				s.logMessage(messageError, err.Error())
+			}
+			continue
+		}
+		if err := s.respond(msg.ID, result, err); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) handle(method string, params json.RawMessage) (any, error) {
+	switch method {
+	case "initialize":
+		return s.initialize(params)
+	case "initialized":
+		s.analyze()
+		return nil, nil
+	case "shutdown":
+		return nil, nil
+	case "exit":
+		return nil, errExit
+	case "textDocument/didOpen":
+		return nil, s.didOpen(params)
+	case "textDocument/didChange":
+		return nil, s.didChange(params)
+	case "textDocument/didSave":
+		s.analyze()
+		return nil, nil
+	case "textDocument/didClose":
+		return nil, s.didClose(params)
+	case "textDocument/codeAction":
+		return s.codeAction(params)
+	case "workspace/executeCommand":
+		return s.executeCommand(params)
+	default:
+		return nil, &responseError{Code: codeMethodNotFound, Message: fmt.Sprintf("method not found: %s", method)}
+	}
+}
+
+func (s *Server) respond(id *json.RawMessage, result any, err error) error {
+	response := &message{ID: id}
+	if err != nil {
+		var respErr *responseError
+		if !errors.As(err, &respErr) {
+			respErr = &responseError{Code: codeInternalError, Message: err.Error()}
+		}
+		response.Error = respErr
+		return writeMessage(s.writer, response)
+	}
+	response.Result, err = json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	return writeMessage(s.writer, response)
+}
+
+func (e *responseError) Error() string {
+	return e.Message
+}
+
+func (s *Server) notify(method string, params any) {
+	contents, err := json.Marshal(params)
+	if err != nil {
+		return
+	}
+	_ = writeMessage(s.writer, &message{Method: method, Params: contents})
+}
+
+func (s *Server) logMessage(messageType int, text string) {
+	s.notify("window/logMessage", showMessageParams{Type: messageType, Message: text})
+}
+
+func (s *Server) initialize(params json.RawMessage) (any, error) {
+	initParams := initializeParams{}
+	if err := json.Unmarshal(params, &initParams); err != nil {
+		return nil, err
+	}
+	switch {
+	case initParams.RootURI != "":
+		s.root = uriToPath(initParams.RootURI)
+	case len(initParams.WorkspaceFolders) > 0:
+		s.root = uriToPath(initParams.WorkspaceFolders[0].URI)
+	default:
+		s.root = initParams.RootPath
+	}
+	if s.root == "" {
+		return nil, errors.New("schoner requires a workspace root")
+	}
+
+	return map[string]any{
+		"capabilities": map[string]any{
+			"textDocumentSync": map[string]any{
+				"openClose": true,
+				"change":    syncFull,
+				"save":      map[string]any{"includeText": false},
+			},
+			"codeActionProvider": true,
+			"executeCommandProvider": map[string]any{
+				"commands": []string{CommandShowReachabilityPath},
+			},
+		},
+		"serverInfo": map[string]any{"name": "schoner"},
+	}, nil
+}
+
+func (s *Server) didOpen(params json.RawMessage) error {
+	openParams := didOpenTextDocumentParams{}
+	if err := json.Unmarshal(params, &openParams); err != nil {
+		return err
+	}
+	path := uriToPath(openParams.TextDocument.URI)
+	s.overlay[path] = []byte(openParams.TextDocument.Text)
+
+	// Opening a document only changes our view of it if it was already modified elsewhere.
+	onDisk, err := os.ReadFile(path)
+	if err != nil || !bytes.Equal(onDisk, s.overlay[path]) {
+		s.analyze()
+	}
+	return nil
+}
+
+func (s *Server) didChange(params json.RawMessage) error {
+	changeParams := didChangeTextDocumentParams{}
+	if err := json.Unmarshal(params, &changeParams); err != nil {
+		return err
+	}
+	if len(changeParams.ContentChanges) == 0 {
+		return nil
+	}
+	// We only advertise full document sync, so the last change holds the whole document.
+	path := uriToPath(changeParams.TextDocument.URI)
+	s.overlay[path] = []byte(changeParams.ContentChanges[len(changeParams.ContentChanges)-1].Text)
+	s.analyze()
+	return nil
+}
+
+func (s *Server) didClose(params json.RawMessage) error {
+	closeParams := didCloseTextDocumentParams{}
+	if err := json.Unmarshal(params, &closeParams); err != nil {
+		return err
+	}
+	delete(s.overlay, uriToPath(closeParams.TextDocument.URI))
+	s.analyze()
+	return nil
+}
+
+// analyze rebuilds the analysis of the workspace and publishes its diagnostics.
+// If the analysis fails the previous one is kept, along with its diagnostics.
+func (s *Server) analyze() {
+	if s.root == "" {
+		return
+	}
+	overlay := make(map[string][]byte, len(s.overlay))
+	for path, contents := range s.overlay {
+		overlay[path] = contents
+	}
+	analysis, err := project.Analyze(s.root, project.WithWalkOptions(walk.WithOverlay(overlay)))
+	if err != nil {
+		s.stale = true
+		s.logMessage(messageError, fmt.Sprintf("failed to analyze %s: %s", s.root, err))
+		return
+	}
+	s.analysis = analysis
+	s.stale = false
+	s.publishDiagnostics()
+}
+
+func (s *Server) publishDiagnostics() {
+	diagnostics := map[string][]diagnostic{}
+	for _, path := range sortedKeys(s.analysis.FileInfos) {
+		diagnostics[pathToURI(path)] = []diagnostic{}
+	}
+	contents := map[string][]byte{}
+	for _, decl := range s.analysis.Unreachable.Sorted(fileinfo.Declaration.Less) {
+		filename := decl.Parent.Filename
+		if _, ok := contents[filename]; !ok {
+			contents[filename] = s.readFile(filename)
+		}
+		uri := pathToURI(filename)
+		diagnostics[uri] = append(diagnostics[uri], unreachableDiagnostic(contents[filename], decl))
+	}
+	// Clear the diagnostics of any file which has since been deleted.
+	for uri := range s.published {
+		if _, ok := diagnostics[uri]; !ok {
+			diagnostics[uri] = []diagnostic{}
+		}
+	}
+
+	for _, uri := range sortedKeys(diagnostics) {
+		params := publishDiagnosticsParams{URI: uri, Diagnostics: diagnostics[uri]}
+		contents, err := json.Marshal(params)
+		if err != nil {
+			continue
+		}
+		if previous, ok := s.published[uri]; ok && bytes.Equal(previous, contents) {
+			continue
+		}
+		if len(params.Diagnostics) == 0 && s.published[uri] == nil {
+			continue
+		}
+		s.notify("textDocument/publishDiagnostics", params)
+		if len(params.Diagnostics) == 0 {
+			delete(s.published, uri)
+		} else {
+			s.published[uri] = contents
+		}
+	}
+}
+
+func unreachableDiagnostic(contents []byte, decl fileinfo.Declaration) diagnostic {
+	return diagnostic{
+		Range:    declarationRange(contents, decl),
+		Severity: severityHint,
+		Tags:     []int{tagUnnecessary},
+		Source:   "schoner",
+		Message:  fmt.Sprintf("%s is unreachable from any entrypoint", decl.Name),
+	}
+}
+
+func declarationRange(contents []byte, decl fileinfo.Declaration) lspRange {
+	return lspRange{
+		Start: toPosition(contents, decl.Pos.Offset),
+		End:   toPosition(contents, decl.End.Offset),
+	}
+}
+
+func (s *Server) codeAction(params json.RawMessage) (any, error) {
+	actionParams := codeActionParams{}
+	if err := json.Unmarshal(params, &actionParams); err != nil {
+		return nil, err
+	}
+	actions := []codeAction{}
+	if s.analysis == nil || s.stale {
+		return actions, nil
+	}
+
+	uri := actionParams.TextDocument.URI
+	path := uriToPath(uri)
+	fileInfo, ok := s.analysis.FileInfos[path]
+	if !ok {
+		return actions, nil
+	}
+	contents := s.readFile(path)
+
+	decls := []fileinfo.Declaration{}
+	for _, decl := range fileInfo.Declarations {
+		declRange := declarationRange(contents, decl)
+		if declRange.End.Line < actionParams.Range.Start.Line || declRange.Start.Line > actionParams.Range.End.Line {
+			continue
+		}
+		decls = append(decls, decl)
+	}
+	sort.Slice(decls, func(i, j int) bool {
+		return decls[i].Less(decls[j])
+	})
+
+	for _, decl := range decls {
+		if !s.analysis.Unreachable.Contains(decl) {
+			continue
+		}
+		diagnostics := []diagnostic{unreachableDiagnostic(contents, decl)}
+		if !sharesLines(fileInfo, decl) {
+			actions = append(actions, codeAction{
+				Title:       fmt.Sprintf("Delete unreachable %s", decl.Name),
+				Kind:        codeActionQuickFix,
+				Diagnostics: diagnostics,
+				Edit: &workspaceEdit{Changes: map[string][]textEdit{
+					uri: {deleteDeclarationEdit(contents, decl)},
+				}},
+			})
+		}
+		actions = append(actions, codeAction{
+			Title:       fmt.Sprintf("Keep %s with %s", decl.Name, fileinfo.KeepDirective),
+			Kind:        codeActionQuickFix,
+			Diagnostics: diagnostics,
+			Edit: &workspaceEdit{Changes: map[string][]textEdit{
+				uri: {keepDeclarationEdit(contents, decl)},
+			}},
+		})
+	}
+
+	offset := toOffset(contents, actionParams.Range.Start)
+	if decl, ok := s.analysis.DeclarationAt(path, offset); ok {
+		actions = append(actions, codeAction{
+			Title: fmt.Sprintf("Show reachability path of %s", decl.Name),
+			Command: &command{
+				Title:     fmt.Sprintf("Show reachability path of %s", decl.Name),
+				Command:   CommandShowReachabilityPath,
+				Arguments: []any{uri, actionParams.Range.Start},
+			},
+		})
+	}
+	return actions, nil
+}
+
+// sharesLines reports whether any line of `decl` also belongs to a declaration beside it,
+// e.g. another name in `var a, b = 1, 2`, in which case deleting its lines would delete that declaration too.
+// Declarations nested in one another, like an interface and its methods, don't count.
+func sharesLines(fileInfo *fileinfo.FileInfo, decl fileinfo.Declaration) bool {
+	for _, other := range fileInfo.Declarations {
+		if other == decl || other.End.Line < decl.Pos.Line || other.Pos.Line > decl.End.Line {
+			continue
+		}
+		nested := other.Pos.Offset >= decl.Pos.Offset && other.End.Offset <= decl.End.Offset
+		containing := decl.Pos.Offset >= other.Pos.Offset && decl.End.Offset <= other.End.Offset
+		if !nested && !containing {
+			return true
+		}
+	}
+	return false
+}
+
+// deleteDeclarationEdit removes every line of `decl`, along with the comment directly above it.
+func deleteDeclarationEdit(contents []byte, decl fileinfo.Declaration) textEdit {
+	lines := strings.Split(string(contents), "\n")
+	start := decl.Pos.Line - 1
+	for start > 0 && strings.HasPrefix(strings.TrimSpace(lines[start-1]), "//") {
+		start--
+	}
+	return textEdit{
+		Range: lspRange{
+			Start: position{Line: start},
+			End:   position{Line: decl.End.Line},
+		},
+	}
+}
+
+// keepDeclarationEdit adds the keep directive directly above `decl`,
+// at the same indentation, so that it becomes part of the declaration's doc comment.
+func keepDeclarationEdit(contents []byte, decl fileinfo.Declaration) textEdit {
+	lines := strings.Split(string(contents), "\n")
+	line := ""
+	if decl.Pos.Line-1 < len(lines) {
+		line = lines[decl.Pos.Line-1]
+	}
+	indentation := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+	return textEdit{
+		Range: lspRange{
+			Start: position{Line: decl.Pos.Line - 1},
+			End:   position{Line: decl.Pos.Line - 1},
+		},
+		NewText: fmt.Sprintf("%s%s\n", indentation, fileinfo.KeepDirective),
+	}
+}
+
+func (s *Server) executeCommand(params json.RawMessage) (any, error) {
+	commandParams := executeCommandParams{}
+	if err := json.Unmarshal(params, &commandParams); err != nil {
+		return nil, err
+	}
+	if commandParams.Command != CommandShowReachabilityPath {
+		return nil, fmt.Errorf("unknown command `%s`", commandParams.Command)
+	}
+	if len(commandParams.Arguments) != 2 {
+		return nil, fmt.Errorf("%s expects a document URI and a position", CommandShowReachabilityPath)
+	}
+	var uri string
+	if err := json.Unmarshal(commandParams.Arguments[0], &uri); err != nil {
+		return nil, err
+	}
+	var pos position
+	if err := json.Unmarshal(commandParams.Arguments[1], &pos); err != nil {
+		return nil, err
+	}
+	if s.analysis == nil {
+		return nil, errors.New("the workspace has not been analyzed yet")
+	}
+	if s.stale {
+		return nil, errors.New("the workspace has changed since it was last analyzed, and can't be analyzed until it parses")
+	}
+
+	path := uriToPath(uri)
+	decl, ok := s.analysis.DeclarationAt(path, toOffset(s.readFile(path), pos))
+	if !ok {
+		return nil, errors.New("there is no declaration at this position")
+	}
+
+	text := ""
+	if reachabilityPath := s.analysis.ReachabilityPath(decl); reachabilityPath == nil {
+		text = fmt.Sprintf("%s is unreachable from any entrypoint", decl.Name)
+	} else {
+		names := make([]string, 0, len(reachabilityPath))
+		for _, step := range reachabilityPath {
+			name, err := s.analysis.DeclarationName(step)
+			if err != nil {
+				return nil, err
+			}
+			names = append(names, name)
+		}
+		text = strings.Join(names, " -> ")
+	}
+	s.notify("window/showMessage", showMessageParams{Type: messageInfo, Message: text})
+	return text, nil
+}
+
+// readFile reads the contents of `path` as the client currently sees them.
+func (s *Server) readFile(path string) []byte {
+	contents, err := walk.ReadFile(path, walk.WithOverlay(s.overlay))
+	if err != nil {
+		return nil
+	}
+	return contents
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func uriToPath(uri string) string {
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.Scheme != "file" {
+		return uri
+	}
+	return filepath.Clean(parsed.Path)
+}
+
+func pathToURI(path string) string {
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
@@ -0,0 +1,224 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/crockeo/schoner/internal/testproject"
+	"github.com/crockeo/schoner/pkg/project"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const mainContents string = `package main
+
+func main() {
+	live()
+}
+
+func live() {}
+
+// dead is never called.
+func dead() {}
+`
+
+func writeRequest(t *testing.T, w *bytes.Buffer, id int, method string, params any) {
+	contents, err := json.Marshal(params)
+	require.NoError(t, err)
+	msg := &message{Method: method, Params: contents}
+	if id != 0 {
+		rawID := json.RawMessage(fmt.Sprint(id))
+		msg.ID = &rawID
+	}
+	require.NoError(t, writeMessage(w, msg))
+}
+
+func readMessages(t *testing.T, r *bytes.Buffer) []*message {
+	reader := bufio.NewReader(r)
+	messages := []*message{}
+	for reader.Buffered() > 0 || r.Len() > 0 {
+		msg, err := readMessage(reader)
+		require.NoError(t, err)
+		messages = append(messages, msg)
+	}
+	return messages
+}
+
+func TestServer(t *testing.T) {
+	root := testproject.Write(t, map[string]string{"main.go": mainContents})
+	mainPath := filepath.Join(root, "main.go")
+	mainURI := pathToURI(mainPath)
+
+	input := &bytes.Buffer{}
+	writeRequest(t, input, 1, "initialize", map[string]any{"rootUri": pathToURI(root)})
+	writeRequest(t, input, 0, "initialized", map[string]any{})
+	writeRequest(t, input, 2, "textDocument/codeAction", map[string]any{
+		"textDocument": map[string]any{"uri": mainURI},
+		"range":        lspRange{Start: position{Line: 9, Character: 5}, End: position{Line: 9, Character: 5}},
+	})
+	writeRequest(t, input, 3, "workspace/executeCommand", map[string]any{
+		"command":   CommandShowReachabilityPath,
+		"arguments": []any{mainURI, position{Line: 6, Character: 5}},
+	})
+	// Calling `dead` from `main` without saving should make it reachable.
+	writeRequest(t, input, 0, "textDocument/didOpen", map[string]any{
+		"textDocument": map[string]any{"uri": mainURI, "text": mainContents},
+	})
+	writeRequest(t, input, 0, "textDocument/didChange", map[string]any{
+		"textDocument":   map[string]any{"uri": mainURI},
+		"contentChanges": []any{map[string]any{"text": strings.Replace(mainContents, "live()\n", "live()\n\tdead()\n", 1)}},
+	})
+	writeRequest(t, input, 4, "shutdown", nil)
+	writeRequest(t, input, 0, "exit", nil)
+
+	output := &bytes.Buffer{}
+	require.NoError(t, NewServer(input, output).Serve())
+
+	messages := readMessages(t, output)
+	require.Len(t, messages, 7)
+
+	assert.Equal(t, "textDocument/publishDiagnostics", messages[1].Method)
+	diagnostics := publishDiagnosticsParams{}
+	require.NoError(t, json.Unmarshal(messages[1].Params, &diagnostics))
+	assert.Equal(t, mainURI, diagnostics.URI)
+	require.Len(t, diagnostics.Diagnostics, 1)
+	assert.Equal(t, "dead is unreachable from any entrypoint", diagnostics.Diagnostics[0].Message)
+	assert.Equal(t, lspRange{Start: position{Line: 9}, End: position{Line: 9, Character: 14}}, diagnostics.Diagnostics[0].Range)
+	assert.Equal(t, []int{tagUnnecessary}, diagnostics.Diagnostics[0].Tags)
+
+	actions := []codeAction{}
+	require.NoError(t, json.Unmarshal(messages[2].Result, &actions))
+	require.Len(t, actions, 3)
+	assert.Equal(t, "Delete unreachable dead", actions[0].Title)
+	assert.Equal(
+		t,
+		[]textEdit{{Range: lspRange{Start: position{Line: 8}, End: position{Line: 10}}}},
+		actions[0].Edit.Changes[mainURI],
+	)
+	assert.Equal(t, "Keep dead with //schoner:keep", actions[1].Title)
+	assert.Equal(
+		t,
+		[]textEdit{{Range: lspRange{Start: position{Line: 9}, End: position{Line: 9}}, NewText: "//schoner:keep\n"}},
+		actions[1].Edit.Changes[mainURI],
+	)
+	assert.Equal(t, CommandShowReachabilityPath, actions[2].Command.Command)
+
+	assert.Equal(t, "window/showMessage", messages[3].Method)
+	var reachabilityPath string
+	require.NoError(t, json.Unmarshal(messages[4].Result, &reachabilityPath))
+	assert.Equal(t, "main.go::main -> main.go::live", reachabilityPath)
+
+	assert.Equal(t, "textDocument/publishDiagnostics", messages[5].Method)
+	require.NoError(t, json.Unmarshal(messages[5].Params, &diagnostics))
+	assert.Empty(t, diagnostics.Diagnostics)
+
+	assert.Equal(t, json.RawMessage("null"), messages[6].Result)
+}
+
+func TestPositions(t *testing.T) {
+	contents := []byte("a\nhéllo 𝄞x\n")
+	assert.Equal(t, position{Line: 1, Character: 8}, toPosition(contents, 13))
+	assert.Equal(t, 13, toOffset(contents, position{Line: 1, Character: 8}))
+	assert.Equal(t, len(contents), toOffset(contents, position{Line: 5}))
+}
+
+// responses returns the responses among `messages`, by their request's ID.
+func responses(messages []*message) map[string]*message {
+	byID := map[string]*message{}
+	for _, msg := range messages {
+		if msg.ID != nil {
+			byID[string(*msg.ID)] = msg
+		}
+	}
+	return byID
+}
+
+func TestServerStale(t *testing.T) {
+	root := testproject.Write(t, map[string]string{"main.go": mainContents})
+	mainURI := pathToURI(filepath.Join(root, "main.go"))
+
+	input := &bytes.Buffer{}
+	writeRequest(t, input, 1, "initialize", map[string]any{"rootUri": pathToURI(root)})
+	writeRequest(t, input, 0, "initialized", map[string]any{})
+	writeRequest(t, input, 0, "textDocument/didOpen", map[string]any{
+		"textDocument": map[string]any{"uri": mainURI, "text": mainContents},
+	})
+	writeRequest(t, input, 0, "textDocument/didChange", map[string]any{
+		"textDocument":   map[string]any{"uri": mainURI},
+		"contentChanges": []any{map[string]any{"text": strings.Replace(mainContents, "live()\n", "live(\n", 1)}},
+	})
+	writeRequest(t, input, 2, "textDocument/codeAction", map[string]any{
+		"textDocument": map[string]any{"uri": mainURI},
+		"range":        lspRange{Start: position{Line: 9, Character: 5}, End: position{Line: 9, Character: 5}},
+	})
+	writeRequest(t, input, 3, "workspace/executeCommand", map[string]any{
+		"command":   CommandShowReachabilityPath,
+		"arguments": []any{mainURI, position{Line: 6, Character: 5}},
+	})
+	writeRequest(t, input, 4, "shutdown", nil)
+	writeRequest(t, input, 0, "exit", nil)
+
+	output := &bytes.Buffer{}
+	require.NoError(t, NewServer(input, output).Serve())
+	byID := responses(readMessages(t, output))
+
+	// Neither edits nor reports are based on an analysis which no longer matches the documents.
+	assert.Equal(t, json.RawMessage("[]"), byID["2"].Result)
+	require.NotNil(t, byID["3"].Error)
+	assert.Contains(t, byID["3"].Error.Message, "can't be analyzed")
+}
+
+const interfaceContents string = `package main
+
+func main() {}
+
+type plugin interface {
+	run()
+}
+`
+
+func TestServerKeepInterfaceMethod(t *testing.T) {
+	root := testproject.Write(t, map[string]string{"main.go": interfaceContents})
+	mainURI := pathToURI(filepath.Join(root, "main.go"))
+
+	input := &bytes.Buffer{}
+	writeRequest(t, input, 1, "initialize", map[string]any{"rootUri": pathToURI(root)})
+	writeRequest(t, input, 0, "initialized", map[string]any{})
+	writeRequest(t, input, 2, "textDocument/codeAction", map[string]any{
+		"textDocument": map[string]any{"uri": mainURI},
+		"range":        lspRange{Start: position{Line: 5, Character: 1}, End: position{Line: 5, Character: 1}},
+	})
+	writeRequest(t, input, 3, "shutdown", nil)
+	writeRequest(t, input, 0, "exit", nil)
+
+	output := &bytes.Buffer{}
+	require.NoError(t, NewServer(input, output).Serve())
+	actions := []codeAction{}
+	require.NoError(t, json.Unmarshal(responses(readMessages(t, output))["2"].Result, &actions))
+
+	var keep *codeAction
+	for i := range actions {
+		if actions[i].Title == "Keep plugin::run with //schoner:keep" {
+			keep = &actions[i]
+		}
+	}
+	require.NotNil(t, keep)
+	edits := keep.Edit.Changes[mainURI]
+	require.Len(t, edits, 1)
+	assert.Equal(t, textEdit{Range: lspRange{Start: position{Line: 5}, End: position{Line: 5}}, NewText: "\t//schoner:keep\n"}, edits[0])
+
+	// Applying the edit keeps the method.
+	lines := strings.SplitAfter(interfaceContents, "\n")
+	kept := strings.Join(lines[:5], "") + edits[0].NewText + strings.Join(lines[5:], "")
+	root = testproject.Write(t, map[string]string{"main.go": kept})
+	analysis, err := project.Analyze(root)
+	require.NoError(t, err)
+	unreachable, err := analysis.DeclarationNames(analysis.Unreachable.ToSlice())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"main.go::plugin"}, unreachable)
+}
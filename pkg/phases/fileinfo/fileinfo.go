@@ -7,48 +7,177 @@ import (
 	"go/parser"
 	"go/token"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"unicode"
 
 	"github.com/crockeo/schoner/pkg/astutil"
 	"github.com/crockeo/schoner/pkg/set"
 	"github.com/crockeo/schoner/pkg/walk"
+	"golang.org/x/mod/modfile"
 )
 
 var (
 	ErrImportPathNotString = errors.New("import path is not a string")
 )
 
+// KeepDirective marks a declaration as an entrypoint when it appears in the declaration's doc comment,
+// for code which is used in ways schoner can't see, e.g. through reflection.
+const KeepDirective = "//schoner:keep"
+
+// Compiler directives which make a declaration used from outside of Go code.
+const (
+	// ExportDirective exports a function to C through cgo.
+	ExportDirective = "//export"
+	// EmbedDirective populates a variable with files from the package's directory.
+	EmbedDirective = "//go:embed"
+	// LinknameDirective links a declaration to a symbol in another package,
+	// either pushing a declaration with a body to it, or pulling it into a declaration without a body.
+	LinknameDirective = "//go:linkname"
+)
+
+// FileInfo describes the declarations in a single file.
+//
+// Entrypoints are always reachable, while the declarations in InitTime,
+// which run when the package is initialized, are only reachable if the package is.
+// InitTime contains init functions, and variables whose initializers call a function.
+// Declarations exported to C, populated by go:embed, or pushed through go:linkname are entrypoints.
 type FileInfo struct {
 	Filename     string
 	Package      string
+	ImportPath   string
 	Entrypoints  set.Set[string]
+	InitTime     set.Set[string]
 	Declarations map[string]Declaration
 	Imports      set.Set[Import]
+	Types        map[string]TypeInfo
+	// Generated is set for files with a `// Code generated ... DO NOT EDIT.` header.
+	Generated bool
+	// Linknames maps each function without a body which is pulled through go:linkname
+	// to the symbol it links to, e.g. `runtime.nanotime` or `example.com/pkg.(*T).method`.
+	Linknames map[string]string
 }
 
 type Declaration struct {
 	Parent *FileInfo
 	Name   string
+	Kind   DeclarationKind
 	Pos    token.Position
 	End    token.Position
 }
 
+// Less orders declarations by their filename, and then by their position within the file.
+func (d Declaration) Less(other Declaration) bool {
+	if d.Parent.Filename != other.Parent.Filename {
+		return d.Parent.Filename < other.Parent.Filename
+	}
+	if d.Pos.Offset != other.Pos.Offset {
+		return d.Pos.Offset < other.Pos.Offset
+	}
+	return d.Name < other.Name
+}
+
+// DeclarationKind describes which sort of top-level declaration produced a Declaration.
+type DeclarationKind int
+
+const (
+	KindFunc DeclarationKind = iota
+	KindMethod
+	KindType
+	KindVar
+	KindConst
+)
+
+func (k DeclarationKind) String() string {
+	switch k {
+	case KindFunc:
+		return "func"
+	case KindMethod:
+		return "method"
+	case KindType:
+		return "type"
+	case KindVar:
+		return "var"
+	case KindConst:
+		return "const"
+	default:
+		return fmt.Sprintf("DeclarationKind(%d)", int(k))
+	}
+}
+
+// TypeInfo records the members of a struct or interface type,
+// so that fields and methods promoted through embedding can be resolved.
+type TypeInfo struct {
+	// Fields contains the name of every field, including embedded fields,
+	// and of every method declared in an interface.
+	Fields    set.Set[string]
+	Embeds    []TypeRef
+	Interface bool
+}
+
+// TypeRef refers to a named type,
+// declared in the package imported as Package, or in the current package when Package is empty.
+type TypeRef struct {
+	Package string
+	Name    string
+}
+
+// TypeRefOf finds the named type referred to by a type expression, e.g. `T`, `*T`, `pkg.T`, or `T[U]`.
+func TypeRefOf(expr ast.Expr) (TypeRef, bool) {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		return TypeRef{Name: expr.Name}, true
+	case *ast.StarExpr:
+		return TypeRefOf(expr.X)
+	case *ast.ParenExpr:
+		return TypeRefOf(expr.X)
+	case *ast.IndexExpr:
+		return TypeRefOf(expr.X)
+	case *ast.IndexListExpr:
+		return TypeRefOf(expr.X)
+	case *ast.SelectorExpr:
+		pkg, ok := expr.X.(*ast.Ident)
+		if !ok {
+			return TypeRef{}, false
+		}
+		return TypeRef{Package: pkg.Name, Name: expr.Sel.Name}, true
+	default:
+		return TypeRef{}, false
+	}
+}
+
 type Import struct {
 	Name string
 	Path string
 }
 
 func FindFileInfos(root string, option walk.Option) (map[string]*FileInfo, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	goModContents, err := os.ReadFile(filepath.Join(root, "go.mod"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find go module root: %w", err)
+	}
+	modulePath := modfile.ModulePath(goModContents)
+	if modulePath == "" {
+		return nil, fmt.Errorf("failed to parse module path from go module in `%s`", root)
+	}
+
 	fileset := token.NewFileSet()
 	fileInfos := map[string]*FileInfo{}
-	err := walk.GoFiles(root, option, func(path string) error {
-		contents, err := os.ReadFile(path)
+	unnamed := map[string][]string{}
+	err = walk.GoFiles(root, option, func(path string) error {
+		contents, err := walk.ReadFile(path, option)
 		if err != nil {
 			return err
 		}
-		fileAst, err := parser.ParseFile(fileset, path, contents, 0)
+		fileAst, err := parser.ParseFile(fileset, path, contents, parser.ParseComments)
 		if err != nil {
 			return fmt.Errorf("failed to parse AST for `%s`: %w", path, err)
 		}
@@ -56,24 +185,74 @@ func FindFileInfos(root string, option walk.Option) (map[string]*FileInfo, error
 		if err != nil {
 			return fmt.Errorf("failed to file info for `%s`: %w", path, err)
 		}
+		fileInfo.ImportPath, err = fileImportPath(path, root, modulePath)
+		if err != nil {
+			return err
+		}
 		fileInfos[path] = fileInfo
+		unnamed[path] = unnamedImports(fileAst)
 		return nil
 	})
 	if err != nil {
 		return nil, err
 	}
+
+	// Packages in the module are named by their package clauses,
+	// rather than by guessing from their import paths.
+	// When the files of a directory disagree, e.g. because one of them is a generator in package main,
+	// the first file in lexical order which isn't an external test names the package.
+	paths := make([]string, 0, len(fileInfos))
+	for path := range fileInfos {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	packageNames := map[string]string{}
+	for _, path := range paths {
+		fileInfo := fileInfos[path]
+		if strings.HasSuffix(fileInfo.Package, "_test") {
+			continue
+		}
+		if _, ok := packageNames[fileInfo.ImportPath]; !ok {
+			packageNames[fileInfo.ImportPath] = fileInfo.Package
+		}
+	}
+	for path, fileInfo := range fileInfos {
+		nameImports(fileInfo, unnamed[path], nil, packageNames)
+	}
 	return fileInfos, nil
 }
 
+// fileImportPath guesses the import path of the package containing `filename`
+// from its directory relative to the module at `root`.
+func fileImportPath(filename string, root string, modulePath string) (string, error) {
+	if !strings.HasPrefix(filename, root) {
+		return "", fmt.Errorf("file %s does not start with root %s", filename, root)
+	}
+	suffix := filename[len(root):]
+	suffix = strings.TrimPrefix(suffix, "/")
+
+	importPath := modulePath
+	if suffix != "" {
+		importPath = filepath.Join(importPath, filepath.Dir(suffix))
+	}
+
+	return importPath, nil
+}
+
 func parseFileInfo(fileset *token.FileSet, filename string, fileAst *ast.File) (*FileInfo, error) {
 	fileInfo := &FileInfo{
 		Package:      fileAst.Name.Name,
 		Filename:     filename,
+		Generated:    isGenerated(fileAst),
 		Entrypoints:  set.NewSet[string](),
+		InitTime:     set.NewSet[string](),
 		Declarations: map[string]Declaration{},
 		Imports:      set.NewSet[Import](),
+		Types:        map[string]TypeInfo{},
+		Linknames:    map[string]string{},
 	}
 
+	bodiless := set.NewSet[string]()
 	for _, decl := range fileAst.Decls {
 		switch decl := decl.(type) {
 		case *ast.FuncDecl:
@@ -81,15 +260,26 @@ func parseFileInfo(fileset *token.FileSet, filename string, fileAst *ast.File) (
 			if err != nil {
 				return nil, err
 			}
+			kind := KindFunc
+			if decl.Recv != nil {
+				kind = KindMethod
+			}
 			fileInfo.Declarations[name] = Declaration{
 				Parent: fileInfo,
 				Name:   name,
+				Kind:   kind,
 				Pos:    fileset.Position(decl.Pos()),
 				End:    fileset.Position(decl.End()),
 			}
-			isInitFunc := name == "init"
+			if name == "init" {
+				fileInfo.InitTime.Add(name)
+			}
+			if decl.Body == nil {
+				bodiless.Add(name)
+			}
 			isMainFunc := fileInfo.Package == "main" && name == "main"
-			if isInitFunc || isMainFunc || isTestFuncDecl(decl) {
+			_, isExported := findDirective(ExportDirective, decl.Doc)
+			if isMainFunc || isTestFuncDecl(decl) || isExported || hasKeepDirective(decl.Doc) {
 				fileInfo.Entrypoints.Add(name)
 			}
 
@@ -101,7 +291,7 @@ func parseFileInfo(fileset *token.FileSet, filename string, fileAst *ast.File) (
 						return nil, ErrImportPathNotString
 					}
 					path := strings.Trim(spec.Path.Value, "\"")
-					name := filepath.Base(path)
+					name := GuessPackageName(path)
 					if spec.Name != nil {
 						name = spec.Name.Name
 					}
@@ -113,22 +303,73 @@ func parseFileInfo(fileset *token.FileSet, filename string, fileAst *ast.File) (
 					fileInfo.Declarations[spec.Name.Name] = Declaration{
 						Parent: fileInfo,
 						Name:   spec.Name.Name,
+						Kind:   KindType,
 						Pos:    fileset.Position(spec.Pos()),
 						End:    fileset.Position(spec.End()),
 					}
+					if hasKeepDirective(decl.Doc, spec.Doc) {
+						fileInfo.Entrypoints.Add(spec.Name.Name)
+					}
+					if typeInfo, ok := parseTypeInfo(spec.Type); ok {
+						fileInfo.Types[spec.Name.Name] = typeInfo
+					}
+					if iface, ok := spec.Type.(*ast.InterfaceType); ok {
+						// Interface methods are declarations in their own right,
+						// so that they can be referenced like any other method.
+						for _, field := range iface.Methods.List {
+							if _, ok := field.Type.(*ast.FuncType); !ok {
+								continue
+							}
+							for _, methodName := range field.Names {
+								name := astutil.Qualify(spec.Name.Name, methodName.Name)
+								fileInfo.Declarations[name] = Declaration{
+									Parent: fileInfo,
+									Name:   name,
+									Kind:   KindMethod,
+									Pos:    fileset.Position(field.Pos()),
+									End:    fileset.Position(field.End()),
+								}
+								if hasKeepDirective(field.Doc) {
+									fileInfo.Entrypoints.Add(name)
+								}
+							}
+						}
+					}
 				case *ast.ValueSpec:
-					for _, name := range spec.Names {
+					kind := KindVar
+					if decl.Tok == token.CONST {
+						kind = KindConst
+					}
+					for i, name := range spec.Names {
 						if name.Name == "_" {
 							// TODO: unify this and the other branch in references.go
 							// Typically values named `_` are intentionally unused,
 							// and are used to assert that structs abide by interfaces.
 							continue
 						}
+						// When a spec declares several names, each spans only its own name,
+						// since their values may be shared.
+						pos, end := spec.Pos(), spec.End()
+						values := spec.Values
+						if len(spec.Names) > 1 {
+							pos, end = name.Pos(), name.End()
+							if len(spec.Values) == len(spec.Names) {
+								values = spec.Values[i : i+1]
+							}
+						}
 						fileInfo.Declarations[name.Name] = Declaration{
 							Parent: fileInfo,
 							Name:   name.Name,
-							Pos:    fileset.Position(spec.Pos()),
-							End:    fileset.Position(spec.End()),
+							Kind:   kind,
+							Pos:    fileset.Position(pos),
+							End:    fileset.Position(end),
+						}
+						_, isEmbedded := findDirective(EmbedDirective, decl.Doc, spec.Doc)
+						if isEmbedded || hasKeepDirective(decl.Doc, spec.Doc) {
+							fileInfo.Entrypoints.Add(name.Name)
+						}
+						if kind == KindVar && hasCall(values) {
+							fileInfo.InitTime.Add(name.Name)
 						}
 					}
 				}
@@ -136,9 +377,190 @@ func parseFileInfo(fileset *token.FileSet, filename string, fileAst *ast.File) (
 		}
 	}
 
+	// go:linkname directives may appear anywhere in the file, rather than only in doc comments.
+	for _, group := range fileAst.Comments {
+		for _, comment := range group.List {
+			args, ok := directiveArgs(LinknameDirective, comment)
+			if !ok || len(args) == 0 {
+				continue
+			}
+			local := args[0]
+			if _, ok := fileInfo.Declarations[local]; !ok {
+				continue
+			}
+			if len(args) > 1 && bodiless.Contains(local) {
+				fileInfo.Linknames[local] = args[1]
+			} else {
+				fileInfo.Entrypoints.Add(local)
+			}
+		}
+	}
+
 	return fileInfo, nil
 }
 
+// hasCall reports whether evaluating any of `exprs` calls a function,
+// not counting calls inside of function literals which aren't themselves called.
+// Conversions, like `T(x)`, are indistinguishable from calls here.
+func hasCall(exprs []ast.Expr) bool {
+	found := false
+	for _, expr := range exprs {
+		ast.Inspect(expr, func(node ast.Node) bool {
+			switch node.(type) {
+			case *ast.CallExpr:
+				found = true
+			case *ast.FuncLit:
+				return false
+			}
+			return !found
+		})
+	}
+	return found
+}
+
+// GuessPackageName guesses the name of the package imported by `importPath`,
+// for packages whose package clause isn't available.
+//
+// It follows the conventions used by goimports: major version suffixes are skipped,
+// so both `example.com/foo/v2` and `gopkg.in/foo.v2` are named `foo`,
+// a `go-` prefix is dropped, and the name ends at the first character which can't appear in an identifier,
+// so `example.com/go-foo-bar` is named `foo`.
+func GuessPackageName(importPath string) string {
+	base := path.Base(importPath)
+	if strings.HasPrefix(base, "v") {
+		if _, err := strconv.Atoi(base[1:]); err == nil {
+			if dir := path.Dir(importPath); dir != "." {
+				base = path.Base(dir)
+			}
+		}
+	}
+	base = strings.TrimPrefix(base, "go-")
+	if i := strings.IndexFunc(base, func(r rune) bool {
+		return !(unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_')
+	}); i >= 0 {
+		base = base[:i]
+	}
+	return base
+}
+
+// unnamedImports returns the path of every import in `fileAst` which isn't explicitly named.
+func unnamedImports(fileAst *ast.File) []string {
+	paths := []string{}
+	for _, spec := range fileAst.Imports {
+		if spec.Name == nil {
+			paths = append(paths, strings.Trim(spec.Path.Value, "\""))
+		}
+	}
+	return paths
+}
+
+// nameImports renames the unnamed imports of `fileInfo`, whose paths are `unnamed`, after the packages they import,
+// which parseFileInfo can only guess from their paths.
+// `importMap` maps import paths to the paths of the packages they resolve to, e.g. for vendored packages.
+func nameImports(fileInfo *FileInfo, unnamed []string, importMap map[string]string, packageNames map[string]string) {
+	for _, path := range unnamed {
+		resolved := path
+		if mapped, ok := importMap[path]; ok {
+			resolved = mapped
+		}
+		name, ok := packageNames[resolved]
+		if !ok || name == "" {
+			continue
+		}
+		guess := Import{Name: GuessPackageName(path), Path: path}
+		if guess.Name == name || !fileInfo.Imports.Contains(guess) {
+			continue
+		}
+		fileInfo.Imports.Remove(guess)
+		fileInfo.Imports.Add(Import{Name: name, Path: path})
+	}
+}
+
+// parseTypeInfo records the fields, methods, and embedded types of a struct or interface type.
+func parseTypeInfo(expr ast.Expr) (TypeInfo, bool) {
+	var fields *ast.FieldList
+	isStruct := false
+	switch expr := expr.(type) {
+	case *ast.StructType:
+		fields = expr.Fields
+		isStruct = true
+	case *ast.InterfaceType:
+		fields = expr.Methods
+	default:
+		return TypeInfo{}, false
+	}
+
+	typeInfo := TypeInfo{Fields: set.NewSet[string](), Interface: !isStruct}
+	for _, field := range fields.List {
+		for _, name := range field.Names {
+			typeInfo.Fields.Add(name.Name)
+		}
+		if len(field.Names) > 0 {
+			continue
+		}
+		// Interfaces may also embed type constraints, like `~int | string`, which have no members.
+		ref, ok := TypeRefOf(field.Type)
+		if !ok {
+			continue
+		}
+		if isStruct {
+			typeInfo.Fields.Add(ref.Name)
+		}
+		typeInfo.Embeds = append(typeInfo.Embeds, ref)
+	}
+	return typeInfo, true
+}
+
+var generatedPattern = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
+// isGenerated reports whether the file has a comment before its package clause
+// which marks it as generated, following https://go.dev/s/generatedcode
+func isGenerated(fileAst *ast.File) bool {
+	for _, group := range fileAst.Comments {
+		if group.Pos() > fileAst.Package {
+			break
+		}
+		for _, comment := range group.List {
+			if generatedPattern.MatchString(comment.Text) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasKeepDirective reports whether any of the comment groups contains KeepDirective,
+// optionally followed by an explanation.
+func hasKeepDirective(groups ...*ast.CommentGroup) bool {
+	_, ok := findDirective(KeepDirective, groups...)
+	return ok
+}
+
+// findDirective returns the arguments of the first comment in any of the comment groups which is `directive`.
+func findDirective(directive string, groups ...*ast.CommentGroup) ([]string, bool) {
+	for _, group := range groups {
+		if group == nil {
+			continue
+		}
+		for _, comment := range group.List {
+			if args, ok := directiveArgs(directive, comment); ok {
+				return args, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// directiveArgs returns the whitespace-separated arguments of `comment` if it's `directive`,
+// e.g. `[]string{"local", "runtime.nanotime"}` for `//go:linkname local runtime.nanotime`.
+func directiveArgs(directive string, comment *ast.Comment) ([]string, bool) {
+	rest, ok := strings.CutPrefix(comment.Text, directive)
+	if !ok || (rest != "" && rest[0] != ' ' && rest[0] != '\t') {
+		return nil, false
+	}
+	return strings.Fields(rest), true
+}
+
 func isTestFuncDecl(decl *ast.FuncDecl) bool {
 	name := decl.Name.Name
 	if !strings.HasPrefix(name, "Test") {
@@ -3,9 +3,12 @@ package fileinfo
 import (
 	"go/parser"
 	"go/token"
+	"path/filepath"
 	"testing"
 
+	"github.com/crockeo/schoner/internal/testproject"
 	"github.com/crockeo/schoner/pkg/set"
+	"github.com/crockeo/schoner/pkg/walk"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -55,21 +58,27 @@ func TestParseFileInfo(t *testing.T) {
 			Filename:    "/fake/file",
 			Package:     "main",
 			Entrypoints: set.NewSet("main"),
+			InitTime:    set.NewSet[string](),
 			Declarations: map[string]Declaration{
 				"main": {
 					Name: "main",
+					Kind: KindFunc,
 				},
 				"otherThing": {
 					Name: "otherThing",
+					Kind: KindFunc,
 				},
 				"StructType": {
 					Name: "StructType",
+					Kind: KindType,
 				},
 				"variable": {
 					Name: "variable",
+					Kind: KindVar,
 				},
 				"constant": {
 					Name: "constant",
+					Kind: KindConst,
 				},
 			},
 			Imports: set.NewSet(
@@ -86,7 +95,167 @@ func TestParseFileInfo(t *testing.T) {
 					Path: "github.com/crockeo/schoner/unnamedimport",
 				},
 			),
+			Types: map[string]TypeInfo{
+				"StructType": {Fields: set.NewSet[string]()},
+			},
+			Linknames: map[string]string{},
 		},
 		fileInfo,
 	)
 }
+
+const keepDirectiveContents string = `
+package lib
+
+//schoner:keep called through reflection
+func Kept() {}
+
+//schoner:keeper
+func NotKept() {}
+
+//schoner:keep
+type KeptType struct{}
+
+var (
+	//schoner:keep
+	keptVar    = 1
+	notKeptVar = 2
+)
+
+type Plugin interface {
+	//schoner:keep looked up by name
+	Kept()
+	NotKept()
+}
+`
+
+func TestParseFileInfoKeepDirective(t *testing.T) {
+	fileset := token.NewFileSet()
+	fileAst, err := parser.ParseFile(fileset, "/fake/file", keepDirectiveContents, parser.ParseComments)
+	require.NoError(t, err)
+	fileInfo, err := parseFileInfo(fileset, "/fake/file", fileAst)
+	require.NoError(t, err)
+
+	assert.Equal(t, set.NewSet("Kept", "KeptType", "keptVar", "Plugin::Kept"), fileInfo.Entrypoints)
+}
+
+const directivesContents string = `
+package lib
+
+import (
+	"embed"
+	_ "unsafe"
+)
+
+//export Exported
+func Exported() {}
+
+//exported elsewhere
+func notExported() {}
+
+//go:embed static
+var static embed.FS
+
+//go:linkname pushed example.com/other.pushed
+func pushed() {}
+
+//go:linkname nanotime runtime.nanotime
+func nanotime() int64
+
+//go:linkname missing runtime.missing
+`
+
+func TestParseFileInfoDirectives(t *testing.T) {
+	fileset := token.NewFileSet()
+	fileAst, err := parser.ParseFile(fileset, "/fake/file", directivesContents, parser.ParseComments)
+	require.NoError(t, err)
+	fileInfo, err := parseFileInfo(fileset, "/fake/file", fileAst)
+	require.NoError(t, err)
+
+	assert.Equal(t, set.NewSet("Exported", "static", "pushed"), fileInfo.Entrypoints)
+	assert.Equal(t, map[string]string{"nanotime": "runtime.nanotime"}, fileInfo.Linknames)
+}
+
+func TestParseFileInfoGenerated(t *testing.T) {
+	tests := map[string]bool{
+		"// Code generated by protoc-gen-go. DO NOT EDIT.\n\npackage lib\n":              true,
+		"// Copyright 2023\n\n// Code generated by mockgen. DO NOT EDIT.\npackage lib\n": true,
+		"package lib\n\n// Code generated by protoc-gen-go. DO NOT EDIT.\n":              false,
+		"// Code generated by hand, please edit.\npackage lib\n":                         false,
+	}
+	for contents, expected := range tests {
+		fileset := token.NewFileSet()
+		fileAst, err := parser.ParseFile(fileset, "/fake/file", contents, parser.ParseComments)
+		require.NoError(t, err)
+		fileInfo, err := parseFileInfo(fileset, "/fake/file", fileAst)
+		require.NoError(t, err)
+		assert.Equal(t, expected, fileInfo.Generated, contents)
+	}
+}
+
+func TestGuessPackageName(t *testing.T) {
+	tests := map[string]string{
+		"fmt":                         "fmt",
+		"net/http":                    "http",
+		"gopkg.in/yaml.v3":            "yaml",
+		"github.com/foo/go-bar":       "bar",
+		"github.com/foo/bar/v2":       "bar",
+		"github.com/foo/bar-go":       "bar",
+		"github.com/go-chi/chi/v5":    "chi",
+		"github.com/mattn/go-sqlite3": "sqlite3",
+		"github.com/foo/v2":           "foo",
+	}
+	for importPath, expected := range tests {
+		assert.Equal(t, expected, GuessPackageName(importPath), importPath)
+	}
+}
+
+func TestFindFileInfosImportNames(t *testing.T) {
+	root := testproject.Write(t, map[string]string{
+		"go.mod":            "module example.com/root\n\ngo 1.20\n",
+		"main.go":           "package main\n\nimport (\n\t\"example.com/root/lib/v2\"\n\t\"gopkg.in/yaml.v3\"\n)\n\nfunc main() { library.Run(); yaml.Marshal(nil) }\n",
+		"lib/v2/library.go": "package library\n\nfunc Run() {}\n",
+	})
+
+	fileInfos, err := FindFileInfos(root, walk.WithOptions())
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		set.NewSet(
+			Import{Name: "library", Path: "example.com/root/lib/v2"},
+			Import{Name: "yaml", Path: "gopkg.in/yaml.v3"},
+		),
+		fileInfos[filepath.Join(root, "main.go")].Imports,
+	)
+}
+
+func TestFindFileInfosMixedPackageNames(t *testing.T) {
+	root := testproject.Write(t, map[string]string{
+		"main.go":          "package main\n\nimport \"example.com/root/impl\"\n\nfunc main() { real.Run() }\n",
+		"impl/a.go":        "package real\n\nfunc Run() {}\n",
+		"impl/a_test.go":   "package real_test\n",
+		"impl/generate.go": "//go:build ignore\n\npackage main\n",
+	})
+
+	// Map iteration order varies, so the name must not depend on it.
+	for i := 0; i < 10; i++ {
+		fileInfos, err := FindFileInfos(root, walk.WithOptions())
+		require.NoError(t, err)
+		assert.Equal(t, set.NewSet(Import{Name: "real", Path: "example.com/root/impl"}), fileInfos[filepath.Join(root, "main.go")].Imports)
+	}
+}
+
+func TestParseFileInfoValueSpecs(t *testing.T) {
+	contents := "package lib\n\nvar first, second = 1, compute()\n"
+	fileset := token.NewFileSet()
+	fileAst, err := parser.ParseFile(fileset, "/fake/file", contents, 0)
+	require.NoError(t, err)
+	fileInfo, err := parseFileInfo(fileset, "/fake/file", fileAst)
+	require.NoError(t, err)
+
+	first := fileInfo.Declarations["first"]
+	second := fileInfo.Declarations["second"]
+	assert.Equal(t, "first", contents[first.Pos.Offset:first.End.Offset])
+	assert.Equal(t, "second", contents[second.Pos.Offset:second.End.Offset])
+	assert.Equal(t, set.NewSet("second"), fileInfo.InitTime)
+}
@@ -0,0 +1,137 @@
+package fileinfo
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/crockeo/schoner/pkg/walk"
+)
+
+// listedPackage is the subset of `go list -json` output used by LoadFileInfos.
+type listedPackage struct {
+	ImportPath   string
+	Name         string
+	Dir          string
+	ForTest      string
+	DepOnly      bool
+	GoFiles      []string
+	CgoFiles     []string
+	TestGoFiles  []string
+	XTestGoFiles []string
+	ImportMap    map[string]string
+	Error        *struct {
+		Err string
+	}
+}
+
+// LoadFileInfos is like FindFileInfos,
+// but asks `go list` which files belong to which packages instead of walking the filesystem.
+//
+// This means that the files are exactly those the go tool would build for the current platform,
+// along with their tests, and excluding vendored code and testdata.
+// Import paths follow the module's real layout (including replace directives),
+// external test packages get their own `_test` import path,
+// and unnamed imports of packages outside of the module are named after the imported package rather than guessed from its path.
+func LoadFileInfos(root string, option walk.Option) (map[string]*FileInfo, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	filter, err := walk.NewFilter(root, option)
+	if err != nil {
+		return nil, err
+	}
+	pkgs, err := goList(root)
+	if err != nil {
+		return nil, err
+	}
+
+	// Test variants and dependencies are only listed to learn the names of imported packages.
+	packageNames := map[string]string{}
+	for _, pkg := range pkgs {
+		packageNames[pkg.ImportPath] = pkg.Name
+	}
+
+	fileset := token.NewFileSet()
+	fileInfos := map[string]*FileInfo{}
+	for _, pkg := range pkgs {
+		if pkg.DepOnly || pkg.ForTest != "" || strings.HasSuffix(pkg.ImportPath, ".test") {
+			continue
+		}
+		if pkg.Error != nil && len(pkg.GoFiles)+len(pkg.CgoFiles) == 0 {
+			return nil, fmt.Errorf("failed to load package `%s`: %s", pkg.ImportPath, pkg.Error.Err)
+		}
+
+		files := map[string][]string{
+			pkg.ImportPath:           append(append(append([]string{}, pkg.GoFiles...), pkg.CgoFiles...), pkg.TestGoFiles...),
+			pkg.ImportPath + "_test": pkg.XTestGoFiles,
+		}
+		for importPath, filenames := range files {
+			for _, filename := range filenames {
+				path := filepath.Join(pkg.Dir, filename)
+				if !filter.Includes(path) {
+					continue
+				}
+				contents, err := walk.ReadFile(path, option)
+				if err != nil {
+					return nil, err
+				}
+				fileAst, err := parser.ParseFile(fileset, path, contents, parser.ParseComments)
+				if err != nil {
+					return nil, fmt.Errorf("failed to parse AST for `%s`: %w", path, err)
+				}
+				fileInfo, err := parseFileInfo(fileset, path, fileAst)
+				if err != nil {
+					return nil, fmt.Errorf("failed to file info for `%s`: %w", path, err)
+				}
+				fileInfo.ImportPath = importPath
+				nameImports(fileInfo, unnamedImports(fileAst), pkg.ImportMap, packageNames)
+				fileInfos[path] = fileInfo
+			}
+		}
+	}
+	return fileInfos, nil
+}
+
+// goList lists every package in the module at `root`, along with their dependencies and test variants.
+func goList(root string) ([]*listedPackage, error) {
+	cmd := exec.Command(
+		"go", "list", "-e", "-deps", "-test",
+		"-json=ImportPath,Name,Dir,ForTest,DepOnly,GoFiles,CgoFiles,TestGoFiles,XTestGoFiles,ImportMap,Error",
+		"./...",
+	)
+	cmd.Dir = root
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("failed to run go list in `%s`: %w: %s", root, err, strings.TrimSpace(stderr.String()))
+	}
+
+	pkgs := []*listedPackage{}
+	decoder := json.NewDecoder(stdout)
+	for {
+		pkg := &listedPackage{}
+		err := decoder.Decode(pkg)
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to parse go list output: %w", err)
+		}
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Slice(pkgs, func(i, j int) bool {
+		return pkgs[i].ImportPath < pkgs[j].ImportPath
+	})
+	return pkgs, nil
+}
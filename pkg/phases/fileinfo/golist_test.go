@@ -0,0 +1,49 @@
+package fileinfo
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/crockeo/schoner/internal/testproject"
+	"github.com/crockeo/schoner/pkg/set"
+	"github.com/crockeo/schoner/pkg/walk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadFileInfos(t *testing.T) {
+	root := testproject.Write(t, map[string]string{
+		"go.mod":                        "module example.com/root\n\ngo 1.20\n",
+		"main.go":                       "package main\n\nimport \"example.com/root/go-bar\"\n\nfunc main() { bar.Bar() }\n",
+		"go-bar/bar.go":                 "package bar\n\nfunc Bar() {}\n",
+		"go-bar/bar_test.go":            "package bar\n\nfunc helper() {}\n",
+		"go-bar/bar_ext_test.go":        "package bar_test\n\nimport \"testing\"\n\nfunc TestBar(t *testing.T) {}\n",
+		"go-bar/testdata/fixture.go":    "package fixture\n",
+		"vendor/example.com/dep/dep.go": "package dep\n",
+	})
+
+	fileInfos, err := LoadFileInfos(root, walk.WithOptions())
+	require.NoError(t, err)
+
+	importPaths := map[string]string{}
+	for path, fileInfo := range fileInfos {
+		rel, err := filepath.Rel(root, path)
+		require.NoError(t, err)
+		importPaths[rel] = fileInfo.ImportPath
+	}
+	assert.Equal(
+		t,
+		map[string]string{
+			"main.go":                "example.com/root",
+			"go-bar/bar.go":          "example.com/root/go-bar",
+			"go-bar/bar_test.go":     "example.com/root/go-bar",
+			"go-bar/bar_ext_test.go": "example.com/root/go-bar_test",
+		},
+		importPaths,
+	)
+	assert.Equal(
+		t,
+		set.NewSet(Import{Name: "bar", Path: "example.com/root/go-bar"}),
+		fileInfos[filepath.Join(root, "main.go")].Imports,
+	)
+}
@@ -0,0 +1,319 @@
+package interfaces
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/crockeo/schoner/pkg/astutil"
+	"github.com/crockeo/schoner/pkg/graph"
+	"github.com/crockeo/schoner/pkg/phases/fileinfo"
+	"github.com/crockeo/schoner/pkg/phases/references"
+	"github.com/crockeo/schoner/pkg/set"
+)
+
+// Status explains why a reachable method is reachable.
+type Status int
+
+const (
+	// StatusReferenced is a method which is referenced directly, e.g. by a call or as a method value,
+	// or which may be, since it's named like a method selected from a value whose type is unknown.
+	StatusReferenced Status = iota
+	// StatusLiveInterface is a method which is only reachable through its receiver type,
+	// and which lets that type implement an interface which is reachable.
+	StatusLiveInterface
+	// StatusDeadInterface is a method which is only reachable through its receiver type,
+	// and which only lets that type implement interfaces which are never used.
+	StatusDeadInterface
+	// StatusReceiverOnly is a method which is only reachable through its receiver type,
+	// and which doesn't help any type implement an interface in the project.
+	// It may still implement an interface from outside of the project which no rule recognizes.
+	StatusReceiverOnly
+	// StatusConvention is a method which is only reachable through its receiver type,
+	// and which is called by convention, as recognized by a rules.Rule, e.g. `String() string`.
+	StatusConvention
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusReferenced:
+		return "referenced"
+	case StatusLiveInterface:
+		return "live via interface"
+	case StatusDeadInterface:
+		return "dead interface"
+	case StatusReceiverOnly:
+		return "receiver only"
+	case StatusConvention:
+		return "called by convention"
+	default:
+		return fmt.Sprintf("Status(%d)", int(s))
+	}
+}
+
+// Method describes why a single reachable method is reachable.
+type Method struct {
+	Declaration fileinfo.Declaration
+	Status      Status
+	// Interfaces are the interfaces which the method helps implement,
+	// for methods with StatusLiveInterface or StatusDeadInterface.
+	Interfaces []fileinfo.Declaration
+	// Rules are the names of the rules which recognize the method, for methods with StatusConvention.
+	Rules []string
+}
+
+// ClassifyMethods explains why each reachable method, declared on a concrete type, is reachable.
+//
+// A type implements an interface when its method set, including methods promoted from embedded types,
+// contains a method named like each method of the interface.
+// Signatures aren't compared, and interfaces which embed an interface from outside of the project are never implemented,
+// since their methods are unknown.
+// `referrers` is `referenceGraph` with every edge reversed.
+func ClassifyMethods(
+	fileInfos map[string]*fileinfo.FileInfo,
+	referenceGraph references.ReferenceGraph,
+	referrers graph.Graph[fileinfo.Declaration],
+	unreachable set.Set[fileinfo.Declaration],
+) []Method {
+	resolver := newTypeResolver(fileInfos)
+
+	// methodImplements maps each method to the interfaces which it helps a type to implement.
+	methodImplements := map[fileinfo.Declaration][]fileinfo.Declaration{}
+	interfaces := []fileinfo.Declaration{}
+	types := []fileinfo.Declaration{}
+	for _, decl := range referenceGraph.SortedNodes(fileinfo.Declaration.Less) {
+		if decl.Kind != fileinfo.KindType {
+			continue
+		}
+		if resolver.isInterface(decl) {
+			interfaces = append(interfaces, decl)
+		} else {
+			types = append(types, decl)
+		}
+	}
+	methodSets := map[fileinfo.Declaration]map[string]fileinfo.Declaration{}
+	for _, typeDecl := range types {
+		methodSets[typeDecl] = resolver.methodSet(typeDecl)
+	}
+	for _, iface := range interfaces {
+		required, ok := resolver.interfaceMethods(iface, set.NewSet[fileinfo.Declaration]())
+		if !ok || required.Len() == 0 {
+			continue
+		}
+		for _, typeDecl := range types {
+			methodSet := methodSets[typeDecl]
+			implemented := []fileinfo.Declaration{}
+			for name := range required {
+				method, ok := methodSet[name]
+				if !ok {
+					break
+				}
+				implemented = append(implemented, method)
+			}
+			if len(implemented) < required.Len() {
+				continue
+			}
+			for _, method := range implemented {
+				methodImplements[method] = appendUnique(methodImplements[method], iface)
+			}
+		}
+	}
+
+	methods := []Method{}
+	for _, decl := range referenceGraph.SortedNodes(fileinfo.Declaration.Less) {
+		if decl.Kind != fileinfo.KindMethod || unreachable.Contains(decl) || resolver.isInterfaceMethod(decl) {
+			continue
+		}
+		method := Method{Declaration: decl, Status: StatusReferenced}
+		name := astutil.Unqualify(decl.Name)[1]
+		declReferrers := referrers.SortedChildren(decl, fileinfo.Declaration.Less)
+		if !referenceGraph.UnresolvedSelectors.Contains(name) && onlyReceiverReferences(referenceGraph, declReferrers, decl) {
+			method.Status = StatusReceiverOnly
+			for _, iface := range methodImplements[decl] {
+				method.Interfaces = append(method.Interfaces, iface)
+				if !unreachable.Contains(iface) {
+					method.Status = StatusLiveInterface
+				} else if method.Status != StatusLiveInterface {
+					method.Status = StatusDeadInterface
+				}
+			}
+			if conventions := referenceGraph.Conventions[decl]; len(conventions) > 0 && method.Status != StatusLiveInterface {
+				method.Status = StatusConvention
+				method.Rules = conventions
+			}
+		}
+		methods = append(methods, method)
+	}
+	return methods
+}
+
+// onlyReceiverReferences reports whether every reference to `decl`, from each of `referrers`,
+// is an implicit reference from its receiver type.
+func onlyReceiverReferences(
+	referenceGraph references.ReferenceGraph,
+	referrers []fileinfo.Declaration,
+	decl fileinfo.Declaration,
+) bool {
+	for _, from := range referrers {
+		for _, ref := range referenceGraph.EdgeReferences(from, decl) {
+			if ref.Kind != references.KindReceiver && ref.Kind != references.KindConvention {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func appendUnique(decls []fileinfo.Declaration, decl fileinfo.Declaration) []fileinfo.Declaration {
+	for _, existing := range decls {
+		if existing == decl {
+			return decls
+		}
+	}
+	return append(decls, decl)
+}
+
+// typeResolver finds the declarations of types, and of their methods, from the file infos alone.
+type typeResolver struct {
+	// declarations maps each import path to the declarations in its package, by name.
+	declarations map[string]map[string]fileinfo.Declaration
+	// methods maps each type to the methods declared directly on it, by name.
+	methods map[fileinfo.Declaration]map[string]fileinfo.Declaration
+}
+
+func newTypeResolver(fileInfos map[string]*fileinfo.FileInfo) *typeResolver {
+	tr := &typeResolver{
+		declarations: map[string]map[string]fileinfo.Declaration{},
+		methods:      map[fileinfo.Declaration]map[string]fileinfo.Declaration{},
+	}
+	paths := make([]string, 0, len(fileInfos))
+	for path := range fileInfos {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		fileInfo := fileInfos[path]
+		if _, ok := tr.declarations[fileInfo.ImportPath]; !ok {
+			tr.declarations[fileInfo.ImportPath] = map[string]fileinfo.Declaration{}
+		}
+		for name, decl := range fileInfo.Declarations {
+			tr.declarations[fileInfo.ImportPath][name] = decl
+		}
+	}
+	for _, decls := range tr.declarations {
+		for name, decl := range decls {
+			if decl.Kind != fileinfo.KindMethod {
+				continue
+			}
+			parts := astutil.Unqualify(name)
+			typeDecl, ok := decls[parts[0]]
+			if !ok {
+				continue
+			}
+			if _, ok := tr.methods[typeDecl]; !ok {
+				tr.methods[typeDecl] = map[string]fileinfo.Declaration{}
+			}
+			tr.methods[typeDecl][parts[1]] = decl
+		}
+	}
+	return tr
+}
+
+// resolve finds the declaration of the type `ref`, as referred to from `fileInfo`.
+func (tr *typeResolver) resolve(fileInfo *fileinfo.FileInfo, ref fileinfo.TypeRef) (fileinfo.Declaration, bool) {
+	importPaths := []string{}
+	for importDecl := range fileInfo.Imports {
+		if importDecl.Name == ref.Package || (ref.Package == "" && importDecl.Name == ".") {
+			importPaths = append(importPaths, importDecl.Path)
+		}
+	}
+	if ref.Package == "" {
+		importPaths = append([]string{fileInfo.ImportPath}, importPaths...)
+	}
+	for _, importPath := range importPaths {
+		if decl, ok := tr.declarations[importPath][ref.Name]; ok && decl.Kind == fileinfo.KindType {
+			return decl, true
+		}
+	}
+	return fileinfo.Declaration{}, false
+}
+
+func (tr *typeResolver) typeInfo(decl fileinfo.Declaration) (fileinfo.TypeInfo, bool) {
+	typeInfo, ok := decl.Parent.Types[decl.Name]
+	return typeInfo, ok
+}
+
+func (tr *typeResolver) isInterface(decl fileinfo.Declaration) bool {
+	typeInfo, ok := tr.typeInfo(decl)
+	return ok && typeInfo.Interface
+}
+
+// isInterfaceMethod reports whether `decl` is a method declared in an interface, rather than on a concrete type.
+func (tr *typeResolver) isInterfaceMethod(decl fileinfo.Declaration) bool {
+	typeInfo, ok := decl.Parent.Types[astutil.Unqualify(decl.Name)[0]]
+	return ok && typeInfo.Interface
+}
+
+// interfaceMethods finds the names of every method of the interface `iface`, including those of the interfaces it embeds.
+// It fails when `iface` embeds a type which isn't an interface in the project.
+func (tr *typeResolver) interfaceMethods(iface fileinfo.Declaration, visited set.Set[fileinfo.Declaration]) (set.Set[string], bool) {
+	names := set.NewSet[string]()
+	if !visited.Add(iface) {
+		return names, true
+	}
+	typeInfo, ok := tr.typeInfo(iface)
+	if !ok {
+		return nil, false
+	}
+	for name := range tr.methods[iface] {
+		names.Add(name)
+	}
+	for _, embed := range typeInfo.Embeds {
+		embedded, ok := tr.resolve(iface.Parent, embed)
+		if !ok || !tr.isInterface(embedded) {
+			return nil, false
+		}
+		embeddedNames, ok := tr.interfaceMethods(embedded, visited)
+		if !ok {
+			return nil, false
+		}
+		names.UnionInPlace(embeddedNames)
+	}
+	return names, true
+}
+
+// methodSet finds every method of the concrete type `typeDecl`, by name,
+// including methods promoted from the types it embeds.
+// Like the compiler, the shallowest method of each name wins.
+func (tr *typeResolver) methodSet(typeDecl fileinfo.Declaration) map[string]fileinfo.Declaration {
+	methodSet := map[string]fileinfo.Declaration{}
+	visited := set.NewSet[fileinfo.Declaration]()
+	queue := []fileinfo.Declaration{typeDecl}
+	for len(queue) > 0 {
+		next := []fileinfo.Declaration{}
+		found := map[string]fileinfo.Declaration{}
+		for _, current := range queue {
+			if !visited.Add(current) {
+				continue
+			}
+			for name, method := range tr.methods[current] {
+				if _, ok := methodSet[name]; !ok {
+					found[name] = method
+				}
+			}
+			typeInfo, ok := tr.typeInfo(current)
+			if !ok || tr.isInterface(current) {
+				continue
+			}
+			for _, embed := range typeInfo.Embeds {
+				if embedded, ok := tr.resolve(current.Parent, embed); ok {
+					next = append(next, embedded)
+				}
+			}
+		}
+		for name, method := range found {
+			methodSet[name] = method
+		}
+		queue = next
+	}
+	return methodSet
+}
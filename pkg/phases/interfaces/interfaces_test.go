@@ -0,0 +1,91 @@
+package interfaces_test
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/crockeo/schoner/internal/testproject"
+	"github.com/crockeo/schoner/pkg/phases/interfaces"
+	"github.com/crockeo/schoner/pkg/project"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const mainContents string = `package main
+
+type Shape interface {
+	Area() int
+}
+
+type Named interface {
+	Name() string
+}
+
+type Labeled interface {
+	Named
+	Label() string
+}
+
+type square struct{}
+
+func (s square) Area() int      { return 1 }
+func (s square) Name() string   { return "square" }
+func (s square) Label() string  { return "" }
+func (s square) Corners() int   { return 4 }
+func (s square) Describe() string { return s.Name() }
+func (s square) String() string { return "[]" }
+
+func main() {
+	var shape Shape = square{}
+	shape.Area()
+	square{}.Describe()
+}
+`
+
+func TestClassifyMethods(t *testing.T) {
+	root := testproject.Write(t, map[string]string{"main.go": mainContents})
+	analysis, err := project.Analyze(root)
+	require.NoError(t, err)
+
+	// Only main references the types, and Labeled and Named are never used.
+	unreachable := []string{}
+	for decl := range analysis.Unreachable {
+		unreachable = append(unreachable, decl.Name)
+	}
+	sort.Strings(unreachable)
+	assert.Equal(t, []string{"Labeled", "Labeled::Label", "Named", "Named::Name"}, unreachable)
+
+	statuses := map[string]interfaces.Status{}
+	implemented := map[string][]string{}
+	conventions := map[string][]string{}
+	for _, method := range analysis.InterfaceMethods() {
+		statuses[method.Declaration.Name] = method.Status
+		for _, iface := range method.Interfaces {
+			implemented[method.Declaration.Name] = append(implemented[method.Declaration.Name], iface.Name)
+		}
+		if len(method.Rules) > 0 {
+			conventions[method.Declaration.Name] = method.Rules
+		}
+	}
+	assert.Equal(
+		t,
+		map[string]interfaces.Status{
+			"square::Area":     interfaces.StatusLiveInterface,
+			"square::Name":     interfaces.StatusReferenced,
+			"square::Label":    interfaces.StatusDeadInterface,
+			"square::Corners":  interfaces.StatusReceiverOnly,
+			"square::Describe": interfaces.StatusReferenced,
+			"square::String":   interfaces.StatusConvention,
+		},
+		statuses,
+	)
+	assert.Equal(
+		t,
+		map[string][]string{
+			"square::Area":  {"Shape"},
+			"square::Label": {"Labeled"},
+		},
+		implemented,
+	)
+	assert.Equal(t, map[string][]string{"square::String": {"fmt.Stringer"}}, conventions)
+}
@@ -0,0 +1,126 @@
+package packagegraph
+
+import (
+	"sort"
+
+	"github.com/crockeo/schoner/pkg/graph"
+	"github.com/crockeo/schoner/pkg/phases/fileinfo"
+	"github.com/crockeo/schoner/pkg/set"
+)
+
+// Package is the set of files which share an import path.
+type Package struct {
+	ImportPath string
+	Files      []*fileinfo.FileInfo
+}
+
+// PackageGraph is a graph of the packages in a project,
+// where each edge points from a package to a package that it imports.
+// Imports of packages outside of the project are omitted.
+type PackageGraph struct {
+	graph.Graph[string]
+	Packages map[string]*Package
+}
+
+func BuildPackageGraph(fileInfos map[string]*fileinfo.FileInfo) PackageGraph {
+	packageGraph := PackageGraph{
+		Graph:    graph.NewGraph[string](),
+		Packages: map[string]*Package{},
+	}
+	for _, fileInfo := range fileInfos {
+		pkg, ok := packageGraph.Packages[fileInfo.ImportPath]
+		if !ok {
+			pkg = &Package{ImportPath: fileInfo.ImportPath}
+			packageGraph.Packages[fileInfo.ImportPath] = pkg
+			packageGraph.AddNode(fileInfo.ImportPath)
+		}
+		pkg.Files = append(pkg.Files, fileInfo)
+	}
+
+	for _, pkg := range packageGraph.Packages {
+		sort.Slice(pkg.Files, func(i, j int) bool {
+			return pkg.Files[i].Filename < pkg.Files[j].Filename
+		})
+		for _, fileInfo := range pkg.Files {
+			for importDecl := range fileInfo.Imports {
+				if importDecl.Path == pkg.ImportPath {
+					continue
+				}
+				if _, ok := packageGraph.Packages[importDecl.Path]; ok {
+					packageGraph.AddEdge(pkg.ImportPath, importDecl.Path)
+				}
+			}
+		}
+	}
+	return packageGraph
+}
+
+// Importers returns the import paths of every package which imports the package at `importPath`.
+func (pg PackageGraph) Importers(importPath string) set.Set[string] {
+	importers := set.NewSet[string]()
+	for importer, imports := range pg.Graph {
+		if imports.Contains(importPath) {
+			importers.Add(importer)
+		}
+	}
+	return importers
+}
+
+// DeadPackages returns every package which has declarations, all of which are unreachable.
+func (pg PackageGraph) DeadPackages(unreachable set.Set[fileinfo.Declaration]) []*Package {
+	deadPackages := []*Package{}
+	for _, pkg := range pg.Packages {
+		hasDeclarations := false
+		allUnreachable := true
+		for _, fileInfo := range pkg.Files {
+			if len(fileInfo.Declarations) > 0 {
+				hasDeclarations = true
+			}
+			if !isDead(fileInfo, unreachable) {
+				allUnreachable = false
+				break
+			}
+		}
+		if hasDeclarations && allUnreachable {
+			deadPackages = append(deadPackages, pkg)
+		}
+	}
+	sort.Slice(deadPackages, func(i, j int) bool {
+		return deadPackages[i].ImportPath < deadPackages[j].ImportPath
+	})
+	return deadPackages
+}
+
+// DeadFiles returns every file which has declarations, all of which are unreachable,
+// and which does not belong to one of the packages returned by DeadPackages.
+func (pg PackageGraph) DeadFiles(unreachable set.Set[fileinfo.Declaration]) []*fileinfo.FileInfo {
+	deadPackages := set.NewSet[string]()
+	for _, pkg := range pg.DeadPackages(unreachable) {
+		deadPackages.Add(pkg.ImportPath)
+	}
+
+	deadFiles := []*fileinfo.FileInfo{}
+	for _, pkg := range pg.Packages {
+		if deadPackages.Contains(pkg.ImportPath) {
+			continue
+		}
+		for _, fileInfo := range pkg.Files {
+			if len(fileInfo.Declarations) > 0 && isDead(fileInfo, unreachable) {
+				deadFiles = append(deadFiles, fileInfo)
+			}
+		}
+	}
+	sort.Slice(deadFiles, func(i, j int) bool {
+		return deadFiles[i].Filename < deadFiles[j].Filename
+	})
+	return deadFiles
+}
+
+func isDead(fileInfo *fileinfo.FileInfo, unreachable set.Set[fileinfo.Declaration]) bool {
+	for _, decl := range fileInfo.Declarations {
+		if !unreachable.Contains(decl) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,56 @@
+package packagegraph
+
+import (
+	"testing"
+
+	"github.com/crockeo/schoner/pkg/phases/fileinfo"
+	"github.com/crockeo/schoner/pkg/set"
+	"github.com/stretchr/testify/assert"
+)
+
+func makeFileInfo(filename string, importPath string, declNames []string, imports ...string) *fileinfo.FileInfo {
+	fileInfo := &fileinfo.FileInfo{
+		Filename:     filename,
+		ImportPath:   importPath,
+		Declarations: map[string]fileinfo.Declaration{},
+		Imports:      set.NewSet[fileinfo.Import](),
+	}
+	for _, declName := range declNames {
+		fileInfo.Declarations[declName] = fileinfo.Declaration{Parent: fileInfo, Name: declName}
+	}
+	for _, importPath := range imports {
+		fileInfo.Imports.Add(fileinfo.Import{Path: importPath})
+	}
+	return fileInfo
+}
+
+func TestPackageGraph(t *testing.T) {
+	mainFile := makeFileInfo("/root/main.go", "example.com/root", []string{"main"}, "fmt", "example.com/root/live")
+	liveFile := makeFileInfo("/root/live/live.go", "example.com/root/live", []string{"Live"}, "example.com/root/dead")
+	deadFile := makeFileInfo("/root/live/dead.go", "example.com/root/live", []string{"Dead"})
+	deadPkgFile := makeFileInfo("/root/dead/dead.go", "example.com/root/dead", []string{"A", "B"})
+	docFile := makeFileInfo("/root/dead/doc.go", "example.com/root/dead", nil)
+	fileInfos := map[string]*fileinfo.FileInfo{}
+	for _, fileInfo := range []*fileinfo.FileInfo{mainFile, liveFile, deadFile, deadPkgFile, docFile} {
+		fileInfos[fileInfo.Filename] = fileInfo
+	}
+
+	packageGraph := BuildPackageGraph(fileInfos)
+	assert.True(t, packageGraph.ContainsEdge("example.com/root", "example.com/root/live"))
+	assert.True(t, packageGraph.ContainsEdge("example.com/root/live", "example.com/root/dead"))
+	assert.False(t, packageGraph.ContainsNode("fmt"))
+	assert.Equal(t, set.NewSet("example.com/root/live"), packageGraph.Importers("example.com/root/dead"))
+
+	unreachable := set.NewSet(
+		deadFile.Declarations["Dead"],
+		deadPkgFile.Declarations["A"],
+		deadPkgFile.Declarations["B"],
+	)
+
+	deadPackages := packageGraph.DeadPackages(unreachable)
+	assert.Len(t, deadPackages, 1)
+	assert.Equal(t, "example.com/root/dead", deadPackages[0].ImportPath)
+	assert.ElementsMatch(t, []*fileinfo.FileInfo{deadPkgFile, docFile}, deadPackages[0].Files)
+
+	assert.Equal(t, []*fileinfo.FileInfo{deadFile}, packageGraph.DeadFiles(unreachable))
+}
@@ -0,0 +1,325 @@
+package params
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"sort"
+
+	"github.com/crockeo/schoner/pkg/astutil"
+	"github.com/crockeo/schoner/pkg/graph"
+	"github.com/crockeo/schoner/pkg/phases/fileinfo"
+	"github.com/crockeo/schoner/pkg/phases/references"
+	"github.com/crockeo/schoner/pkg/set"
+	"github.com/crockeo/schoner/pkg/walk"
+)
+
+// Kind describes what is unused about a function's signature.
+type Kind int
+
+const (
+	// KindUnusedParam is a parameter which the function never reads.
+	KindUnusedParam Kind = iota
+	// KindDiscardedResult is a result which every caller of the function discards.
+	KindDiscardedResult
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindUnusedParam:
+		return "unused parameter"
+	case KindDiscardedResult:
+		return "discarded result"
+	default:
+		return fmt.Sprintf("Kind(%d)", int(k))
+	}
+}
+
+// Finding is a single parameter or result of a function which could be removed.
+type Finding struct {
+	Declaration fileinfo.Declaration
+	Kind        Kind
+	// Index is the position of the parameter or result in the function's signature.
+	Index int
+	// Name is the name of the parameter or result, or the type of an unnamed result.
+	Name string
+	Pos  token.Position
+}
+
+// Less orders findings by their declaration, and then by their position within its signature.
+func (f Finding) Less(other Finding) bool {
+	if f.Declaration != other.Declaration {
+		return f.Declaration.Less(other.Declaration)
+	}
+	if f.Kind != other.Kind {
+		return f.Kind < other.Kind
+	}
+	return f.Index < other.Index
+}
+
+// FindUnused finds the parameters of reachable functions which are never read,
+// and the results of reachable functions which every caller discards.
+//
+// Functions which must keep their shape are skipped:
+// entrypoints, functions which are used as values rather than only being called, e.g. as callbacks,
+// and methods which may satisfy an interface,
+// i.e. exported methods, methods called by convention, and methods named like any method of an interface in the project.
+// Results are only reported for functions, since calls to methods can't always be resolved.
+// `referrers` leads from each declaration to the declarations which reference it in `referenceGraph`.
+func FindUnused(
+	fileInfos map[string]*fileinfo.FileInfo,
+	referenceGraph references.ReferenceGraph,
+	referrers graph.Graph[fileinfo.Declaration],
+	unreachable set.Set[fileinfo.Declaration],
+	option walk.Option,
+) ([]Finding, error) {
+	fileset := token.NewFileSet()
+	funcDecls := map[fileinfo.Declaration]*ast.FuncDecl{}
+	callSites := map[callSiteKey]callSite{}
+	for path, fileInfo := range fileInfos {
+		contents, err := walk.ReadFile(path, option)
+		if err != nil {
+			return nil, err
+		}
+		fileAst, err := parser.ParseFile(fileset, path, contents, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, decl := range fileAst.Decls {
+			funcDecl, ok := decl.(*ast.FuncDecl)
+			if !ok || funcDecl.Body == nil {
+				continue
+			}
+			name, err := astutil.FunctionName(funcDecl)
+			if err != nil {
+				return nil, err
+			}
+			if decl, ok := fileInfo.Declarations[name]; ok {
+				funcDecls[decl] = funcDecl
+			}
+		}
+		findCallSites(fileset, fileAst, callSites)
+	}
+
+	interfaceMethods := findInterfaceMethods(fileInfos)
+	findings := []Finding{}
+	for decl, funcDecl := range funcDecls {
+		if unreachable.Contains(decl) || decl.Parent.Entrypoints.Contains(decl.Name) || decl.Parent.InitTime.Contains(decl.Name) {
+			continue
+		}
+		calls, onlyCalled := incomingCalls(referenceGraph, referrers, decl)
+		if !onlyCalled {
+			continue
+		}
+		if decl.Kind == fileinfo.KindMethod {
+			methodName := funcDecl.Name.Name
+			_, isConvention := referenceGraph.Conventions[decl]
+			if ast.IsExported(methodName) || interfaceMethods.Contains(methodName) || isConvention {
+				continue
+			}
+		}
+
+		findings = append(findings, unusedParams(fileset, decl, funcDecl)...)
+		if decl.Kind == fileinfo.KindFunc && len(calls) > 0 {
+			findings = append(findings, discardedResults(fileset, decl, funcDecl, calls, callSites)...)
+		}
+	}
+	sort.Slice(findings, func(i, j int) bool {
+		return findings[i].Less(findings[j])
+	})
+	return findings, nil
+}
+
+// callSiteKey identifies a call by the position of the expression being called,
+// which is where the reference graph records the call.
+type callSiteKey struct {
+	filename string
+	offset   int
+}
+
+// callSite records which results of a call are discarded.
+type callSite struct {
+	// discardsAll is set when the call is a statement of its own, e.g. `f()` or `go f()`.
+	discardsAll bool
+	// blank records which results are assigned to `_`, e.g. `x, _ := f()`.
+	blank []bool
+}
+
+func (cs callSite) discards(index int) bool {
+	return cs.discardsAll || (index < len(cs.blank) && cs.blank[index])
+}
+
+// findCallSites records which results of every call in `fileAst` are discarded.
+func findCallSites(fileset *token.FileSet, fileAst *ast.File, callSites map[callSiteKey]callSite) {
+	_ = astutil.Walk(fileAst, func(path []ast.Node, node ast.Node) error {
+		call, ok := node.(*ast.CallExpr)
+		if !ok || len(path) == 0 {
+			return nil
+		}
+		site := callSite{}
+		switch parent := path[len(path)-1].(type) {
+		case *ast.ExprStmt, *ast.GoStmt, *ast.DeferStmt:
+			site.discardsAll = true
+		case *ast.AssignStmt:
+			if len(parent.Rhs) == 1 {
+				site.blank = blankExprs(parent.Lhs)
+			}
+		case *ast.ValueSpec:
+			if len(parent.Values) == 1 {
+				site.blank = blankIdents(parent.Names)
+			}
+		}
+		position := fileset.Position(call.Fun.Pos())
+		callSites[callSiteKey{filename: position.Filename, offset: position.Offset}] = site
+		return nil
+	})
+}
+
+func blankExprs(exprs []ast.Expr) []bool {
+	blank := make([]bool, len(exprs))
+	for i, expr := range exprs {
+		ident, ok := expr.(*ast.Ident)
+		blank[i] = ok && ident.Name == "_"
+	}
+	return blank
+}
+
+func blankIdents(idents []*ast.Ident) []bool {
+	blank := make([]bool, len(idents))
+	for i, ident := range idents {
+		blank[i] = ident.Name == "_"
+	}
+	return blank
+}
+
+// findInterfaceMethods finds the name of every method declared by an interface in the project.
+func findInterfaceMethods(fileInfos map[string]*fileinfo.FileInfo) set.Set[string] {
+	methods := set.NewSet[string]()
+	for _, fileInfo := range fileInfos {
+		for _, decl := range fileInfo.Declarations {
+			if decl.Kind != fileinfo.KindMethod {
+				continue
+			}
+			parts := astutil.Unqualify(decl.Name)
+			if typeInfo, ok := fileInfo.Types[parts[0]]; ok && typeInfo.Interface {
+				methods.Add(parts[1])
+			}
+		}
+	}
+	return methods
+}
+
+// incomingCalls returns every site at which `decl` is called,
+// and whether it's only ever called, as opposed to e.g. being passed as a callback.
+func incomingCalls(
+	referenceGraph references.ReferenceGraph,
+	referrers graph.Graph[fileinfo.Declaration],
+	decl fileinfo.Declaration,
+) ([]references.Reference, bool) {
+	calls := []references.Reference{}
+	for _, from := range referrers.SortedChildren(decl, fileinfo.Declaration.Less) {
+		for _, ref := range referenceGraph.EdgeReferences(from, decl) {
+			switch ref.Kind {
+			case references.KindCall:
+				calls = append(calls, ref)
+			case references.KindReceiver:
+			default:
+				return nil, false
+			}
+		}
+	}
+	return calls, true
+}
+
+// unusedParams finds the named parameters of `funcDecl` which its body never reads.
+func unusedParams(fileset *token.FileSet, decl fileinfo.Declaration, funcDecl *ast.FuncDecl) []Finding {
+	used := set.NewSet[string]()
+	ast.Inspect(funcDecl.Body, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.SelectorExpr:
+			// The selected name is a field or method, not a variable.
+			ast.Inspect(node.X, func(node ast.Node) bool {
+				if ident, ok := node.(*ast.Ident); ok {
+					used.Add(ident.Name)
+				}
+				return true
+			})
+			return false
+		case *ast.Ident:
+			used.Add(node.Name)
+		}
+		return true
+	})
+
+	findings := []Finding{}
+	index := 0
+	for _, field := range funcDecl.Type.Params.List {
+		if len(field.Names) == 0 {
+			index++
+			continue
+		}
+		for _, name := range field.Names {
+			if name.Name != "_" && !used.Contains(name.Name) {
+				findings = append(findings, Finding{
+					Declaration: decl,
+					Kind:        KindUnusedParam,
+					Index:       index,
+					Name:        name.Name,
+					Pos:         fileset.Position(name.Pos()),
+				})
+			}
+			index++
+		}
+	}
+	return findings
+}
+
+// discardedResults finds the results of `funcDecl` which are discarded at every one of its `calls`.
+func discardedResults(
+	fileset *token.FileSet,
+	decl fileinfo.Declaration,
+	funcDecl *ast.FuncDecl,
+	calls []references.Reference,
+	callSites map[callSiteKey]callSite,
+) []Finding {
+	if funcDecl.Type.Results == nil {
+		return nil
+	}
+
+	findings := []Finding{}
+	index := 0
+	for _, field := range funcDecl.Type.Results.List {
+		names := []string{}
+		for _, name := range field.Names {
+			names = append(names, name.Name)
+		}
+		if len(names) == 0 {
+			names = append(names, types.ExprString(field.Type))
+		}
+		for _, name := range names {
+			if discardedByAll(calls, callSites, index) {
+				findings = append(findings, Finding{
+					Declaration: decl,
+					Kind:        KindDiscardedResult,
+					Index:       index,
+					Name:        name,
+					Pos:         fileset.Position(field.Pos()),
+				})
+			}
+			index++
+		}
+	}
+	return findings
+}
+
+func discardedByAll(calls []references.Reference, callSites map[callSiteKey]callSite, index int) bool {
+	for _, call := range calls {
+		site, ok := callSites[callSiteKey{filename: call.Pos.Filename, offset: call.Pos.Offset}]
+		if !ok || !site.discards(index) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,132 @@
+package params_test
+
+import (
+	"testing"
+
+	"github.com/crockeo/schoner/internal/testproject"
+	"github.com/crockeo/schoner/pkg/project"
+	"github.com/crockeo/schoner/pkg/rules"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const mainContents string = `package main
+
+import "sort"
+
+type greeter interface {
+	greet(name string)
+}
+
+type loud struct{}
+
+func (l loud) greet(name string) {}
+
+func (l loud) shout(volume int) {}
+
+func add(a int, b int) (int, error) {
+	return a, nil
+}
+
+func less(i int, j int) bool { return false }
+
+func first[T any](xs []T, fallback T) (T, error) {
+	var zero T
+	return zero, nil
+}
+
+func inner() (int, error) { return 0, nil }
+
+func wrap() (int, error) {
+	inner()
+	return inner()
+}
+
+func dead(unused int) (int, error) { return 0, nil }
+
+//schoner:keep
+func kept(unused int) {}
+
+func main() {
+	sum, _ := add(1, 2)
+	_ = sum
+	add(3, 4)
+	loud{}.shout(1)
+	var g greeter = loud{}
+	g.greet("world")
+	sort.Slice(nil, less)
+	v, _ := first[int](nil, 0)
+	_ = v
+	wrap()
+}
+`
+
+func TestFindUnused(t *testing.T) {
+	root := testproject.Write(t, map[string]string{"main.go": mainContents})
+	analysis, err := project.Analyze(root)
+	require.NoError(t, err)
+	findings, err := analysis.UnusedParams()
+	require.NoError(t, err)
+
+	// greet satisfies an interface, and less is used as a callback, so both must keep their shape.
+	// dead is unreachable and kept is an entrypoint, so neither is reported,
+	// and inner's results are returned by wrap, so they aren't discarded.
+	descriptions := []string{}
+	for _, finding := range findings {
+		descriptions = append(descriptions, finding.Declaration.Name+" "+finding.Kind.String()+" "+finding.Name)
+	}
+	assert.Equal(
+		t,
+		[]string{
+			"loud::shout unused parameter volume",
+			"add unused parameter b",
+			"add discarded result error",
+			"first unused parameter xs",
+			"first unused parameter fallback",
+			"first discarded result error",
+			"wrap discarded result int",
+			"wrap discarded result error",
+		},
+		descriptions,
+	)
+}
+
+// routeRule keeps methods named route, which a hypothetical router calls through reflection.
+type routeRule struct{}
+
+func (routeRule) Name() string {
+	return "router"
+}
+
+func (routeRule) Keeps(decl rules.Declaration) bool {
+	method, ok := decl.Method()
+	return ok && method.Name == "route"
+}
+
+const conventionContents string = `package main
+
+type handler struct{}
+
+func (h handler) route(path string) {}
+
+func (h handler) serve(path string) {}
+
+func main() {
+	handler{}.serve("/")
+}
+`
+
+func TestFindUnusedConventions(t *testing.T) {
+	root := testproject.Write(t, map[string]string{"main.go": conventionContents})
+	analysis, err := project.Analyze(root, project.WithRules(routeRule{}))
+	require.NoError(t, err)
+	findings, err := analysis.UnusedParams()
+	require.NoError(t, err)
+
+	// route is called by convention, so its caller decides its shape.
+	names := []string{}
+	for _, finding := range findings {
+		names = append(names, finding.Declaration.Name+" "+finding.Name)
+	}
+	assert.Equal(t, []string{"handler::serve path"}, names)
+}
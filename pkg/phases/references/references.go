@@ -5,15 +5,15 @@ import (
 	"go/ast"
 	"go/parser"
 	"go/token"
-	"os"
-	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/crockeo/schoner/pkg/astutil"
 	"github.com/crockeo/schoner/pkg/graph"
 	"github.com/crockeo/schoner/pkg/phases/fileinfo"
+	"github.com/crockeo/schoner/pkg/rules"
+	"github.com/crockeo/schoner/pkg/set"
 	"github.com/crockeo/schoner/pkg/walk"
-	"golang.org/x/mod/modfile"
 )
 
 // TODO: find all references in a file
@@ -24,29 +24,146 @@ import (
 //
 // - [x] static imported references, where an ast.SelectorExpr references a declaration in an imported file
 //
-// - [ ] dynamic references, where an ast.SelectorExpr references a field or method on another
+// - [~] dynamic references, where an ast.SelectorExpr references a field or method on another,
+//   which are only resolved when the type of the selected value is evident from its declaration
 
+// Kind describes how a declaration is referenced at a particular site.
+type Kind int
+
+const (
+	// KindCall is a call of a function, e.g. `fn()`.
+	KindCall Kind = iota
+	// KindTypeUse is any use of a type outside of a composite literal,
+	// e.g. in a signature, a field, or a conversion.
+	KindTypeUse
+	// KindValueRead is a use of a value which is not immediately called,
+	// e.g. reading a variable or passing a function as a callback.
+	KindValueRead
+	// KindCompositeLit is the construction of a type through a composite literal, e.g. `T{}`.
+	KindCompositeLit
+	// KindMethodExpr is a method expression, e.g. `T.Method` or `(*T).Method`.
+	KindMethodExpr
+	// KindReceiver is the implicit reference from a type to the methods declared on it.
+	KindReceiver
+	// KindEmbed is the embedding of a type in a struct or interface, which promotes its fields and methods.
+	KindEmbed
+	// KindMember is the selection of a field or method through a value, e.g. `x.Method()` or `x.Field`,
+	// where it refers to a method, or to the embedded type which promotes the field.
+	KindMember
+	// KindConvention is the implicit reference from a type to a method declared on it
+	// which is called by convention, as recognized by a rules.Rule, e.g. `String() string`.
+	KindConvention
+	// KindLinkname is the reference from a function without a body
+	// to the function it pulls in through go:linkname.
+	KindLinkname
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindCall:
+		return "call"
+	case KindTypeUse:
+		return "type use"
+	case KindValueRead:
+		return "value read"
+	case KindCompositeLit:
+		return "composite literal"
+	case KindMethodExpr:
+		return "method expression"
+	case KindReceiver:
+		return "receiver"
+	case KindEmbed:
+		return "embed"
+	case KindMember:
+		return "member"
+	case KindConvention:
+		return "convention"
+	case KindLinkname:
+		return "linkname"
+	default:
+		return fmt.Sprintf("Kind(%d)", int(k))
+	}
+}
+
+// Reference is a single site at which one declaration refers to another.
+type Reference struct {
+	Kind Kind
+	Pos  token.Position
+}
+
+// ReferenceGraph is a graph of declarations,
+// where each edge also records every site at which the reference occurs.
+type ReferenceGraph struct {
+	graph.Graph[fileinfo.Declaration]
+	References map[graph.Edge[fileinfo.Declaration]][]Reference
+	// UnresolvedSelectors contains the names selected from values whose types couldn't be determined,
+	// e.g. `Method` in `f().Method()`, each of which may refer to any field or method of that name.
+	UnresolvedSelectors set.Set[string]
+	// Conventions maps each declaration which is used by convention to the names of the rules which recognize it.
+	Conventions map[fileinfo.Declaration][]string
+}
+
+func NewReferenceGraph() ReferenceGraph {
+	return ReferenceGraph{
+		Graph:               graph.NewGraph[fileinfo.Declaration](),
+		References:          map[graph.Edge[fileinfo.Declaration]][]Reference{},
+		UnresolvedSelectors: set.NewSet[string](),
+		Conventions:         map[fileinfo.Declaration][]string{},
+	}
+}
+
+// AddReference adds an edge from `from` to `to`, if one does not already exist,
+// and records `reference` as one of the sites at which it occurs.
+func (rg ReferenceGraph) AddReference(from fileinfo.Declaration, to fileinfo.Declaration, reference Reference) {
+	rg.AddEdge(from, to)
+	edge := graph.Edge[fileinfo.Declaration]{From: from, To: to}
+	rg.References[edge] = append(rg.References[edge], reference)
+}
+
+// EdgeReferences returns every site at which `from` refers to `to`.
+func (rg ReferenceGraph) EdgeReferences(from fileinfo.Declaration, to fileinfo.Declaration) []Reference {
+	return rg.References[graph.Edge[fileinfo.Declaration]{From: from, To: to}]
+}
+
+// Subgraph returns the subgraph induced by `nodes`,
+// along with the references recorded for each of its edges.
+func (rg ReferenceGraph) Subgraph(nodes set.Set[fileinfo.Declaration]) ReferenceGraph {
+	subgraph := ReferenceGraph{
+		Graph:               rg.Graph.Subgraph(nodes),
+		References:          map[graph.Edge[fileinfo.Declaration]][]Reference{},
+		UnresolvedSelectors: rg.UnresolvedSelectors,
+		Conventions:         rg.Conventions,
+	}
+	for edge, refs := range rg.References {
+		if subgraph.ContainsEdge(edge.From, edge.To) {
+			subgraph.References[edge] = refs
+		}
+	}
+	return subgraph
+}
+
+// BuildReferenceGraph finds every reference between the declarations in `fileInfos`.
+// Declarations recognized by any of `conventions` are recorded in ReferenceGraph.Conventions,
+// and methods among them are also referenced by their receiver type with KindConvention.
 func BuildReferenceGraph(
 	root string,
 	fileInfos map[string]*fileinfo.FileInfo,
 	option walk.Option,
-) (graph.Graph[fileinfo.Declaration], error) {
-	builder, err := newReferenceGraphBuilder(root, fileInfos)
-	if err != nil {
-		return nil, err
-	}
-	fileset := token.NewFileSet()
-	for path, fileInfo := range fileInfos {
-		contents, err := os.ReadFile(path)
+	conventions ...rules.Rule,
+) (ReferenceGraph, error) {
+	builder := newReferenceGraphBuilder(fileInfos, conventions)
+	for _, path := range sortedPaths(fileInfos) {
+		fileInfo := fileInfos[path]
+		contents, err := walk.ReadFile(path, option)
 		if err != nil {
-			return nil, err
+			return ReferenceGraph{}, err
 		}
-		fileAst, err := parser.ParseFile(fileset, path, contents, 0)
+		fileAst, err := parser.ParseFile(builder.Fileset, path, contents, 0)
 		if err != nil {
-			return nil, err
+			return ReferenceGraph{}, err
 		}
 		if err := builder.Visit(path, fileInfo, fileAst); err != nil {
-			return nil, err
+			return ReferenceGraph{}, err
 		}
 	}
 	return builder.ReferenceGraph, nil
@@ -54,32 +171,20 @@ func BuildReferenceGraph(
 
 type referenceGraphBuilder struct {
 	FileInfos         map[string]*fileinfo.FileInfo
-	ReferenceGraph    graph.Graph[fileinfo.Declaration]
-	Root              string
-	RootModule        string
+	Fileset           *token.FileSet
+	ReferenceGraph    ReferenceGraph
 	DeclarationLookup map[string]map[string]fileinfo.Declaration
+	Conventions       []rules.Rule
 }
 
-func newReferenceGraphBuilder(root string, fileInfos map[string]*fileinfo.FileInfo) (*referenceGraphBuilder, error) {
-	goModContents, err := os.ReadFile(filepath.Join(root, "go.mod"))
-	if err != nil {
-		return nil, fmt.Errorf("failed to find go module root: %w", err)
-	}
-	modulePath := modfile.ModulePath(goModContents)
-	if modulePath == "" {
-		return nil, fmt.Errorf("failed to parse module path from go module: %w", err)
-	}
-	declarationLookup, err := makeDeclarationLookup(root, modulePath, fileInfos)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create declaration lookup: %w", err)
-	}
+func newReferenceGraphBuilder(fileInfos map[string]*fileinfo.FileInfo, conventions []rules.Rule) *referenceGraphBuilder {
 	return &referenceGraphBuilder{
 		FileInfos:         fileInfos,
-		ReferenceGraph:    graph.NewGraph[fileinfo.Declaration](),
-		Root:              root,
-		RootModule:        modulePath,
-		DeclarationLookup: declarationLookup,
-	}, nil
+		Fileset:           token.NewFileSet(),
+		ReferenceGraph:    NewReferenceGraph(),
+		DeclarationLookup: makeDeclarationLookup(fileInfos),
+		Conventions:       conventions,
+	}
 }
 
 func (rgb *referenceGraphBuilder) Visit(filename string, fileInfo *fileinfo.FileInfo, fileAst *ast.File) error {
@@ -87,79 +192,230 @@ func (rgb *referenceGraphBuilder) Visit(filename string, fileInfo *fileinfo.File
 		rgb.ReferenceGraph.AddNode(decl)
 	}
 
-	ourModule, err := fileInfoModule(fileInfo, rgb.Root, rgb.RootModule)
-	if err != nil {
-		return fmt.Errorf("failed to determine current module: %w", err)
-	}
+	ourModule := fileInfo.ImportPath
+	var current scope
+	err := astutil.Walk(fileAst, func(path []ast.Node, node ast.Node) error {
+		if len(path) == 1 {
+			current = scope{}
+		}
 
-	err = astutil.Walk(fileAst, func(path []ast.Node, node ast.Node) error {
 		// TODO: where to put this? definitely not here!
-		if node, ok := node.(*ast.FuncDecl); ok {
+		switch node := node.(type) {
+		case *ast.FuncDecl:
+			current.typeParams = funcTypeParams(node)
+			current.locals = localTypes(node, current.typeParams)
 			name, err := astutil.FunctionName(node)
 			if err != nil {
 				return err
 			}
+			rgb.addConventions(fileInfo, []string{name}, node)
 			if !astutil.IsQualified(name) {
 				return nil
 			}
-			container := astutil.Unqualify(name)[0]
-
-			from, ok := rgb.identReference(ourModule, container)
-			if !ok {
-				return nil
+			rgb.addReceiverReference(ourModule, name, node)
+		case *ast.ValueSpec:
+			if isTopLevel(path) {
+				names := []string{}
+				for _, name := range node.Names {
+					names = append(names, name.Name)
+				}
+				rgb.addConventions(fileInfo, names, node)
 			}
-			to, ok := rgb.identReference(ourModule, name)
-			if !ok {
-				return nil
+		case *ast.TypeSpec:
+			if isTopLevel(path) {
+				// Local types can't declare type parameters, but they can use those of their function.
+				current.typeParams = fieldNames(node.TypeParams)
+				rgb.addConventions(fileInfo, []string{node.Name.Name}, node)
+			}
+			if iface, ok := node.Type.(*ast.InterfaceType); ok {
+				for _, field := range iface.Methods.List {
+					for _, methodName := range field.Names {
+						rgb.addReceiverReference(ourModule, astutil.Qualify(node.Name.Name, methodName.Name), field)
+					}
+				}
+			}
+		}
+
+		// Constants declared inside of functions belong to their function, which already refers to what they do.
+		if node, ok := node.(*ast.GenDecl); ok && node.Tok == token.CONST && len(path) == 1 {
+			if err := rgb.visitImplicitConsts(fileInfo, node); err != nil {
+				return err
 			}
-			rgb.ReferenceGraph.AddEdge(from, to)
 		}
 
-		container, err := astutil.OuterDeclName(path)
+		containers, err := astutil.OuterDeclNames(path, node)
 		if err != nil {
 			return nil
 		}
-		if astutil.IsQualified(container) {
-			parts := astutil.Unqualify(container)
-			container = parts[0]
-		}
-		from, ok := rgb.identReference(ourModule, container)
+		target, kind, ok := rgb.resolve(fileInfo, current, path, node)
 		if !ok {
 			return nil
 		}
+		rgb.addReferences(ourModule, containers, target, Reference{
+			Kind: kind,
+			Pos:  rgb.Fileset.Position(node.Pos()),
+		})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	rgb.addLinknameReferences(fileInfo)
+	return nil
+}
 
-		from.Name = container
-		if astutil.IsQualified(from.Name) {
-			parts := astutil.Unqualify(from.Name)
-			from.Name = parts[0]
+// addLinknameReferences adds a reference from each function in `fileInfo` which is pulled through go:linkname
+// to the declaration it links to, when that declaration is in the project.
+func (rgb *referenceGraphBuilder) addLinknameReferences(fileInfo *fileinfo.FileInfo) {
+	for local, symbol := range fileInfo.Linknames {
+		importPath, name, ok := splitLinkname(symbol)
+		if !ok {
+			continue
 		}
-		if from.Name == "_" {
-			// TODO: unify this and the other branch in fileinfo.go
-			// Typically values named `_` are intentionally unused,
-			// and are used to assert that structs abide by interfaces.
-			return nil
+		target, ok := rgb.DeclarationLookup[importPath][name]
+		if !ok {
+			continue
 		}
+		from := fileInfo.Declarations[local]
+		rgb.ReferenceGraph.AddReference(from, target, Reference{
+			Kind: KindLinkname,
+			Pos:  from.Pos,
+		})
+	}
+}
 
-		switch node := node.(type) {
-		case *ast.Ident:
-			target, ok := rgb.identReference(ourModule, node.Name)
-			if ok && from != target {
-				rgb.ReferenceGraph.AddEdge(from, target)
-			}
-		case *ast.SelectorExpr:
-			target, ok := rgb.selectorReference(fileInfo, node)
-			if ok && from != target {
-				rgb.ReferenceGraph.AddEdge(from, target)
+// splitLinkname splits a go:linkname symbol into the import path of its package and the name of its declaration,
+// e.g. `example.com/pkg` and `T::method` for `example.com/pkg.(*T).method`.
+func splitLinkname(symbol string) (string, string, bool) {
+	dir := ""
+	if slash := strings.LastIndex(symbol, "/"); slash >= 0 {
+		dir, symbol = symbol[:slash+1], symbol[slash+1:]
+	}
+	pkg, name, ok := strings.Cut(symbol, ".")
+	if !ok {
+		return "", "", false
+	}
+	if typeName, method, ok := strings.Cut(name, "."); ok {
+		typeName = strings.TrimSuffix(strings.TrimPrefix(typeName, "(*"), ")")
+		name = astutil.Qualify(typeName, method)
+	}
+	return dir + pkg, name, true
+}
+
+// visitImplicitConsts adds the references of the constants in `decl` which implicitly repeat the type and value of the one before them,
+// like the members of an `iota` enum, so that each of them refers to what the repeated expression does.
+func (rgb *referenceGraphBuilder) visitImplicitConsts(fileInfo *fileinfo.FileInfo, decl *ast.GenDecl) error {
+	var repeated *ast.ValueSpec
+	for _, spec := range decl.Specs {
+		spec, ok := spec.(*ast.ValueSpec)
+		if !ok {
+			continue
+		}
+		if spec.Type != nil || len(spec.Values) > 0 {
+			repeated = spec
+			continue
+		}
+		if repeated == nil {
+			continue
+		}
+
+		exprs := append([]ast.Expr{}, repeated.Values...)
+		if repeated.Type != nil {
+			exprs = append(exprs, repeated.Type)
+		}
+		for _, expr := range exprs {
+			err := astutil.Walk(expr, func(path []ast.Node, node ast.Node) error {
+				target, kind, ok := rgb.resolve(fileInfo, scope{}, path, node)
+				if !ok {
+					return nil
+				}
+				for _, name := range spec.Names {
+					rgb.addReferences(fileInfo.ImportPath, []string{name.Name}, target, Reference{
+						Kind: kind,
+						Pos:  rgb.Fileset.Position(name.Pos()),
+					})
+				}
+				return nil
+			})
+			if err != nil {
+				return err
 			}
 		}
-		return nil
-	})
-	if err != nil {
-		return err
 	}
 	return nil
 }
 
+// resolve finds the declaration which `node`, found beneath `path`, refers to, and how it refers to it.
+func (rgb *referenceGraphBuilder) resolve(
+	fileInfo *fileinfo.FileInfo,
+	current scope,
+	path []ast.Node,
+	node ast.Node,
+) (fileinfo.Declaration, Kind, bool) {
+	var target fileinfo.Declaration
+	var kind Kind
+	var ok bool
+	switch node := node.(type) {
+	case *ast.Ident:
+		if current.typeParams.Contains(node.Name) {
+			break
+		}
+		target, ok = rgb.fileIdentReference(fileInfo, node.Name)
+		kind = referenceKind(path, node, target)
+	case *ast.SelectorExpr:
+		target, ok = rgb.selectorReference(fileInfo, node)
+		kind = referenceKind(path, node, target)
+		if !ok {
+			target, ok = rgb.methodExprReference(fileInfo, node)
+			kind = KindMethodExpr
+		}
+		if !ok {
+			target, ok = rgb.memberReference(fileInfo, current.locals, node)
+			kind = KindMember
+			if ok && target.Kind == fileinfo.KindMethod && referenceKind(path, node, target) == KindCall {
+				kind = KindCall
+			}
+		}
+		if !ok && !isImportName(fileInfo, node.X) {
+			rgb.ReferenceGraph.UnresolvedSelectors.Add(node.Sel.Name)
+		}
+	}
+	return target, kind, ok
+}
+
+// isImportName reports whether `expr` is the name of one of the imports of `fileInfo`, like `fmt` in `fmt.Println`.
+func isImportName(fileInfo *fileinfo.FileInfo, expr ast.Expr) bool {
+	ident, ok := expr.(*ast.Ident)
+	if !ok {
+		return false
+	}
+	for importDecl := range fileInfo.Imports {
+		if importDecl.Name == ident.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// addReferences adds `reference` from each of the declarations named `containers` in `ourModule` to `target`.
+// References from methods are attributed to their receiver type.
+func (rgb *referenceGraphBuilder) addReferences(ourModule string, containers []string, target fileinfo.Declaration, reference Reference) {
+	for _, container := range containers {
+		container = astutil.Unqualify(container)[0]
+		if container == "_" {
+			// TODO: unify this and the other branch in fileinfo.go
+			// Typically values named `_` are intentionally unused,
+			// and are used to assert that structs abide by interfaces.
+			continue
+		}
+		from, ok := rgb.identReference(ourModule, container)
+		if !ok || from == target {
+			continue
+		}
+		rgb.ReferenceGraph.AddReference(from, target, reference)
+	}
+}
+
 func (rgb *referenceGraphBuilder) identReference(ourModule string, name string) (fileinfo.Declaration, bool) {
 	declarations, ok := rgb.DeclarationLookup[ourModule]
 	if !ok {
@@ -169,6 +425,23 @@ func (rgb *referenceGraphBuilder) identReference(ourModule string, name string)
 	return declaration, ok
 }
 
+// fileIdentReference resolves an identifier in the file `currentFileInfo`,
+// either to a declaration in its own package or to one in a package it dot-imports.
+func (rgb *referenceGraphBuilder) fileIdentReference(currentFileInfo *fileinfo.FileInfo, name string) (fileinfo.Declaration, bool) {
+	if decl, ok := rgb.identReference(currentFileInfo.ImportPath, name); ok {
+		return decl, true
+	}
+	for importDecl := range currentFileInfo.Imports {
+		if importDecl.Name != "." {
+			continue
+		}
+		if decl, ok := rgb.identReference(importDecl.Path, name); ok {
+			return decl, true
+		}
+	}
+	return fileinfo.Declaration{}, false
+}
+
 func (rgb *referenceGraphBuilder) selectorReference(currentFileInfo *fileinfo.FileInfo, selector *ast.SelectorExpr) (fileinfo.Declaration, bool) {
 	ident, ok := selector.X.(*ast.Ident)
 	if !ok {
@@ -196,19 +469,341 @@ func (rgb *referenceGraphBuilder) selectorReference(currentFileInfo *fileinfo.Fi
 	return decl, ok
 }
 
-func makeDeclarationLookup(
-	root string,
-	rootModule string,
-	fileInfos map[string]*fileinfo.FileInfo,
-) (map[string]map[string]fileinfo.Declaration, error) {
-	// module -> symbol -> declaration
-	declarationLookup := map[string]map[string]fileinfo.Declaration{}
-	for _, fileInfo := range fileInfos {
-		module, err := fileInfoModule(fileInfo, root, rootModule)
-		if err != nil {
-			return nil, err
+// addReceiverReference adds the implicit reference from the type which declares `method` to `method`.
+func (rgb *referenceGraphBuilder) addReceiverReference(ourModule string, method string, node ast.Node) {
+	container := astutil.Unqualify(method)[0]
+	from, ok := rgb.identReference(ourModule, container)
+	if !ok {
+		return
+	}
+	to, ok := rgb.identReference(ourModule, method)
+	if !ok {
+		return
+	}
+	rgb.ReferenceGraph.AddReference(from, to, Reference{
+		Kind: KindReceiver,
+		Pos:  rgb.Fileset.Position(node.Pos()),
+	})
+}
+
+// addConventions records the rules which recognize each of the top-level declarations named `names`, declared by `node`,
+// and adds a reference from the receiver type of each recognized method to the method.
+func (rgb *referenceGraphBuilder) addConventions(fileInfo *fileinfo.FileInfo, names []string, node ast.Node) {
+	for _, name := range names {
+		decl, ok := fileInfo.Declarations[name]
+		if !ok {
+			continue
+		}
+		for _, rule := range rgb.Conventions {
+			if !rule.Keeps(rules.Declaration{Declaration: decl, Node: node}) {
+				continue
+			}
+			rgb.ReferenceGraph.Conventions[decl] = append(rgb.ReferenceGraph.Conventions[decl], rule.Name())
+			if decl.Kind != fileinfo.KindMethod {
+				continue
+			}
+			if from, ok := rgb.identReference(fileInfo.ImportPath, astutil.Unqualify(name)[0]); ok {
+				rgb.ReferenceGraph.AddReference(from, decl, Reference{
+					Kind: KindConvention,
+					Pos:  rgb.Fileset.Position(node.Pos()),
+				})
+			}
+		}
+	}
+}
+
+// methodExprReference resolves method expressions on types declared in our module,
+// such as `T.Method` or `(*T).Method`, including methods promoted from embedded types.
+func (rgb *referenceGraphBuilder) methodExprReference(currentFileInfo *fileinfo.FileInfo, selector *ast.SelectorExpr) (fileinfo.Declaration, bool) {
+	x := selector.X
+	for {
+		paren, ok := x.(*ast.ParenExpr)
+		if !ok {
+			break
+		}
+		x = paren.X
+	}
+	typeName, ok := astutil.ExprName(x)
+	if !ok {
+		return fileinfo.Declaration{}, false
+	}
+	target, ok := rgb.promotedMember(currentFileInfo, fileinfo.TypeRef{Name: typeName}, selector.Sel.Name)
+	if !ok || target.Kind != fileinfo.KindMethod {
+		return fileinfo.Declaration{}, false
+	}
+	return target, true
+}
+
+// memberReference resolves selectors on local variables whose type is known from their declaration,
+// such as `x.Method()` where `x` is a receiver or a parameter of type `T`.
+func (rgb *referenceGraphBuilder) memberReference(
+	currentFileInfo *fileinfo.FileInfo,
+	locals map[string]fileinfo.TypeRef,
+	selector *ast.SelectorExpr,
+) (fileinfo.Declaration, bool) {
+	ident, ok := selector.X.(*ast.Ident)
+	if !ok {
+		return fileinfo.Declaration{}, false
+	}
+	ref, ok := locals[ident.Name]
+	if !ok {
+		return fileinfo.Declaration{}, false
+	}
+	return rgb.promotedMember(currentFileInfo, ref, selector.Sel.Name)
+}
+
+// promotedMember finds the member `name` of the type `ref`, as referred to from `currentFileInfo`.
+//
+// Methods resolve to their declaration, and fields promoted through an embedded type resolve to the type which declares them.
+// Fields declared directly on `ref` aren't resolved, since the type is already referenced wherever it's used.
+// Like the compiler, the shallowest embedded member wins.
+func (rgb *referenceGraphBuilder) promotedMember(
+	currentFileInfo *fileinfo.FileInfo,
+	ref fileinfo.TypeRef,
+	name string,
+) (fileinfo.Declaration, bool) {
+	type embedded struct {
+		fileInfo *fileinfo.FileInfo
+		ref      fileinfo.TypeRef
+	}
+
+	visited := set.NewSet[fileinfo.Declaration]()
+	queue := []embedded{{fileInfo: currentFileInfo, ref: ref}}
+	for depth := 0; len(queue) > 0; depth++ {
+		next := []embedded{}
+		for _, current := range queue {
+			typeDecl, ok := rgb.typeReference(current.fileInfo, current.ref)
+			if !ok || !visited.Add(typeDecl) {
+				continue
+			}
+			method, ok := rgb.identReference(typeDecl.Parent.ImportPath, astutil.Qualify(typeDecl.Name, name))
+			if ok {
+				return method, true
+			}
+			typeInfo := typeDecl.Parent.Types[typeDecl.Name]
+			if typeInfo.Fields.Contains(name) {
+				return typeDecl, depth > 0
+			}
+			for _, embed := range typeInfo.Embeds {
+				next = append(next, embedded{fileInfo: typeDecl.Parent, ref: embed})
+			}
+		}
+		queue = next
+	}
+	return fileinfo.Declaration{}, false
+}
+
+// typeReference finds the declaration of the type `ref`, as referred to from `currentFileInfo`.
+func (rgb *referenceGraphBuilder) typeReference(currentFileInfo *fileinfo.FileInfo, ref fileinfo.TypeRef) (fileinfo.Declaration, bool) {
+	if ref.Package == "" {
+		decl, ok := rgb.fileIdentReference(currentFileInfo, ref.Name)
+		return decl, ok && decl.Kind == fileinfo.KindType
+	}
+	for importDecl := range currentFileInfo.Imports {
+		if importDecl.Name != ref.Package {
+			continue
 		}
+		decl, ok := rgb.identReference(importDecl.Path, ref.Name)
+		return decl, ok && decl.Kind == fileinfo.KindType
+	}
+	return fileinfo.Declaration{}, false
+}
 
+// scope holds what's known about the names declared within the top-level declaration being visited.
+type scope struct {
+	// locals maps local variables to their types, where they're evident.
+	locals map[string]fileinfo.TypeRef
+	// typeParams contains the names of type parameters, which shadow declarations of the same name.
+	typeParams set.Set[string]
+}
+
+// funcTypeParams finds the names of the type parameters of `fn`,
+// including those of its receiver, like `K` and `V` in `func (m Map[K, V]) Get(k K) V`.
+func funcTypeParams(fn *ast.FuncDecl) set.Set[string] {
+	typeParams := fieldNames(fn.Type.TypeParams)
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return typeParams
+	}
+	recv := fn.Recv.List[0].Type
+	if star, ok := recv.(*ast.StarExpr); ok {
+		recv = star.X
+	}
+	var indices []ast.Expr
+	switch recv := recv.(type) {
+	case *ast.IndexExpr:
+		indices = []ast.Expr{recv.Index}
+	case *ast.IndexListExpr:
+		indices = recv.Indices
+	}
+	for _, index := range indices {
+		if ident, ok := index.(*ast.Ident); ok {
+			typeParams.Add(ident.Name)
+		}
+	}
+	return typeParams
+}
+
+// fieldNames returns the names declared by every field in `fields`.
+func fieldNames(fields *ast.FieldList) set.Set[string] {
+	names := set.NewSet[string]()
+	if fields == nil {
+		return names
+	}
+	for _, field := range fields.List {
+		for _, name := range field.Names {
+			names.Add(name.Name)
+		}
+	}
+	return names
+}
+
+// localTypes finds the type of every local variable in `fn` whose type is evident from its declaration:
+// receivers, parameters, variables declared with a type,
+// and variables assigned a composite literal, e.g. `x := T{}` or `x := &T{}`.
+//
+// Variables are keyed by name alone, so a variable which shadows another takes its place everywhere in `fn`.
+// Variables whose type is one of `typeParams` are skipped, since their type isn't known.
+func localTypes(fn *ast.FuncDecl, typeParams set.Set[string]) map[string]fileinfo.TypeRef {
+	locals := map[string]fileinfo.TypeRef{}
+	addFields := func(fields *ast.FieldList) {
+		if fields == nil {
+			return
+		}
+		for _, field := range fields.List {
+			ref, ok := fileinfo.TypeRefOf(field.Type)
+			if !ok {
+				continue
+			}
+			for _, name := range field.Names {
+				locals[name.Name] = ref
+			}
+		}
+	}
+	addFields(fn.Recv)
+	ast.Inspect(fn, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.FuncType:
+			addFields(node.Params)
+			addFields(node.Results)
+		case *ast.ValueSpec:
+			if ref, ok := fileinfo.TypeRefOf(node.Type); ok {
+				for _, name := range node.Names {
+					locals[name.Name] = ref
+				}
+			}
+		case *ast.AssignStmt:
+			if node.Tok != token.DEFINE || len(node.Lhs) != len(node.Rhs) {
+				break
+			}
+			for i, lhs := range node.Lhs {
+				ident, ok := lhs.(*ast.Ident)
+				if !ok {
+					continue
+				}
+				if ref, ok := compositeLitType(node.Rhs[i]); ok {
+					locals[ident.Name] = ref
+				}
+			}
+		}
+		return true
+	})
+	for name, ref := range locals {
+		if ref.Package == "" && typeParams.Contains(ref.Name) {
+			delete(locals, name)
+		}
+	}
+	return locals
+}
+
+// compositeLitType finds the type constructed by `T{}` or `&T{}`.
+func compositeLitType(expr ast.Expr) (fileinfo.TypeRef, bool) {
+	if unary, ok := expr.(*ast.UnaryExpr); ok && unary.Op == token.AND {
+		expr = unary.X
+	}
+	lit, ok := expr.(*ast.CompositeLit)
+	if !ok || lit.Type == nil {
+		return fileinfo.TypeRef{}, false
+	}
+	return fileinfo.TypeRefOf(lit.Type)
+}
+
+// referenceKind classifies how `node`, found beneath `path`, refers to `target`.
+func referenceKind(path []ast.Node, node ast.Node, target fileinfo.Declaration) Kind {
+	// Climb out of any expressions which don't change how `node` is used,
+	// e.g. the selector in `pkg.Name` or the instantiation in `Name[T]`.
+	expr := node
+	i := len(path) - 1
+	for ; i >= 0; i-- {
+		switch p := path[i].(type) {
+		case *ast.ParenExpr:
+			expr = p
+			continue
+		case *ast.SelectorExpr:
+			if p.Sel == expr {
+				expr = p
+				continue
+			}
+		case *ast.IndexExpr:
+			if p.X == expr {
+				expr = p
+				continue
+			}
+		case *ast.IndexListExpr:
+			if p.X == expr {
+				expr = p
+				continue
+			}
+		case *ast.StarExpr:
+			if target.Kind == fileinfo.KindType {
+				expr = p
+				continue
+			}
+		}
+		break
+	}
+
+	if i >= 0 {
+		switch parent := path[i].(type) {
+		case *ast.CallExpr:
+			if parent.Fun == expr && target.Kind != fileinfo.KindType {
+				return KindCall
+			}
+		case *ast.CompositeLit:
+			if parent.Type == expr {
+				return KindCompositeLit
+			}
+		case *ast.Field:
+			if len(parent.Names) == 0 && parent.Type == expr && isTypeMembers(path[:i]) {
+				return KindEmbed
+			}
+		}
+	}
+	if target.Kind == fileinfo.KindType {
+		return KindTypeUse
+	}
+	return KindValueRead
+}
+
+// isTypeMembers reports whether the innermost node of `path` is the field list of a struct or interface type,
+// as opposed to e.g. the parameters of a function.
+func isTypeMembers(path []ast.Node) bool {
+	if len(path) < 2 {
+		return false
+	}
+	switch path[len(path)-2].(type) {
+	case *ast.StructType, *ast.InterfaceType:
+		return true
+	default:
+		return false
+	}
+}
+
+func makeDeclarationLookup(fileInfos map[string]*fileinfo.FileInfo) map[string]map[string]fileinfo.Declaration {
+	// module -> symbol -> declaration
+	declarationLookup := map[string]map[string]fileinfo.Declaration{}
+	for _, path := range sortedPaths(fileInfos) {
+		fileInfo := fileInfos[path]
+		module := fileInfo.ImportPath
 		if _, ok := declarationLookup[module]; !ok {
 			declarationLookup[module] = map[string]fileinfo.Declaration{}
 		}
@@ -217,20 +812,22 @@ func makeDeclarationLookup(
 			declarationLookup[module][declName] = decl
 		}
 	}
-	return declarationLookup, nil
+	return declarationLookup
 }
 
-func fileInfoModule(fileInfo *fileinfo.FileInfo, root string, rootModule string) (string, error) {
-	if !strings.HasPrefix(fileInfo.Filename, root) {
-		return "", fmt.Errorf("file %s does not start with root %s", fileInfo.Filename, root)
-	}
-	suffix := fileInfo.Filename[len(root):]
-	suffix = strings.TrimPrefix(suffix, "/")
+// isTopLevel reports whether the spec at the end of `path` is declared at the top level of its file,
+// rather than inside of a function.
+func isTopLevel(path []ast.Node) bool {
+	return len(path) == 2
+}
 
-	module := rootModule
-	if suffix != "" {
-		module = filepath.Join(module, filepath.Dir(suffix))
+// sortedPaths returns the paths of `fileInfos` in lexical order,
+// so that declarations and references are always discovered in the same order.
+func sortedPaths(fileInfos map[string]*fileinfo.FileInfo) []string {
+	paths := make([]string, 0, len(fileInfos))
+	for path := range fileInfos {
+		paths = append(paths, path)
 	}
-
-	return module, nil
+	sort.Strings(paths)
+	return paths
 }
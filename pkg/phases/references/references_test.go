@@ -0,0 +1,256 @@
+package references
+
+import (
+	"testing"
+
+	"github.com/crockeo/schoner/internal/testproject"
+	"github.com/crockeo/schoner/pkg/phases/fileinfo"
+	"github.com/crockeo/schoner/pkg/walk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func buildReferenceGraph(t *testing.T, files map[string]string) ReferenceGraph {
+	root := testproject.Write(t, files)
+	fileInfos, err := fileinfo.FindFileInfos(root, walk.WithOptions())
+	require.NoError(t, err)
+	referenceGraph, err := BuildReferenceGraph(root, fileInfos, walk.WithOptions())
+	require.NoError(t, err)
+	return referenceGraph
+}
+
+// edgeKinds returns the kinds of every reference between the declarations named `from` and `to`.
+func edgeKinds(referenceGraph ReferenceGraph, from string, to string) []Kind {
+	kinds := []Kind{}
+	for edge, refs := range referenceGraph.References {
+		if edge.From.Name == from && edge.To.Name == to {
+			for _, ref := range refs {
+				kinds = append(kinds, ref.Kind)
+			}
+		}
+	}
+	return kinds
+}
+
+const embeddingContents string = `package main
+
+import "example.com/root/lib"
+
+type Inner struct {
+	count int
+}
+
+func (i *Inner) Increment() {}
+
+type Outer struct {
+	*Inner
+	lib.Closer
+}
+
+func main() {
+	o := &Outer{}
+	o.Increment()
+	_ = o.count
+	o.Close()
+	f := Outer.Increment
+	_ = f
+}
+`
+
+const libContents string = `package lib
+
+type Closer interface {
+	Close() error
+}
+`
+
+func TestBuildReferenceGraphEmbedding(t *testing.T) {
+	referenceGraph := buildReferenceGraph(t, map[string]string{
+		"main.go":    embeddingContents,
+		"lib/lib.go": libContents,
+	})
+
+	assert.Equal(t, []Kind{KindEmbed}, edgeKinds(referenceGraph, "Outer", "Inner"))
+	assert.Equal(t, []Kind{KindEmbed}, edgeKinds(referenceGraph, "Outer", "Closer"))
+	assert.Equal(t, []Kind{KindReceiver}, edgeKinds(referenceGraph, "Closer", "Closer::Close"))
+
+	// Promoted methods and fields are attributed to the embedded type which declares them.
+	assert.ElementsMatch(t, []Kind{KindCall, KindMethodExpr}, edgeKinds(referenceGraph, "main", "Inner::Increment"))
+	assert.Equal(t, []Kind{KindMember}, edgeKinds(referenceGraph, "main", "Inner"))
+	assert.Equal(t, []Kind{KindCall}, edgeKinds(referenceGraph, "main", "Closer::Close"))
+}
+
+const dotImportContents string = `package main
+
+import . "example.com/root/lib"
+
+type closer struct{}
+
+func (c closer) Close() error { return nil }
+
+func main() {
+	var c Closer = closer{}
+	c.Close()
+}
+`
+
+func TestBuildReferenceGraphDotImport(t *testing.T) {
+	referenceGraph := buildReferenceGraph(t, map[string]string{
+		"main.go":    dotImportContents,
+		"lib/lib.go": libContents,
+	})
+
+	assert.Equal(t, []Kind{KindTypeUse}, edgeKinds(referenceGraph, "main", "Closer"))
+	assert.Equal(t, []Kind{KindCall}, edgeKinds(referenceGraph, "main", "Closer::Close"))
+}
+
+const valueSpecContents string = `package main
+
+type Color int
+
+const (
+	Red Color = iota + offset
+	Green
+	Blue
+)
+
+const offset = 1
+
+var first, second = makeFirst(), makeSecond()
+
+func makeFirst() int  { return 1 }
+func makeSecond() int { return 2 }
+
+const scale = 2
+
+func paint() {
+	const (
+		unit = iota * scale
+		second
+	)
+	_ = second
+}
+
+func main() {}
+`
+
+func TestBuildReferenceGraphValueSpecs(t *testing.T) {
+	referenceGraph := buildReferenceGraph(t, map[string]string{"main.go": valueSpecContents})
+
+	// Each name is attributed its own initializer.
+	assert.Equal(t, []Kind{KindCall}, edgeKinds(referenceGraph, "first", "makeFirst"))
+	assert.Empty(t, edgeKinds(referenceGraph, "first", "makeSecond"))
+	assert.Equal(t, []Kind{KindCall}, edgeKinds(referenceGraph, "second", "makeSecond"))
+	assert.Empty(t, edgeKinds(referenceGraph, "first", "second"))
+
+	// Constants which repeat the previous type and value refer to what it does.
+	for _, name := range []string{"Red", "Green", "Blue"} {
+		assert.Equal(t, []Kind{KindTypeUse}, edgeKinds(referenceGraph, name, "Color"), name)
+		assert.Equal(t, []Kind{KindValueRead}, edgeKinds(referenceGraph, name, "offset"), name)
+	}
+	assert.Empty(t, edgeKinds(referenceGraph, "Green", "Red"))
+
+	// Constants declared inside of a function belong to it, even when they shadow a top-level declaration.
+	assert.Equal(t, []Kind{KindValueRead}, edgeKinds(referenceGraph, "paint", "scale"))
+	assert.Empty(t, edgeKinds(referenceGraph, "second", "scale"))
+}
+
+const genericsContents string = `package main
+
+type Number interface {
+	~int | ~float64
+}
+
+type K struct{}
+
+type Map[K comparable, V Number] struct {
+	values map[K]V
+}
+
+func (m *Map[K, V]) Get(key K) V {
+	return m.values[key]
+}
+
+func Sum[T Number](values ...T) T {
+	var total T
+	return total
+}
+
+func Local[K comparable]() {
+	type local struct{}
+	var key K
+	_, _ = local{}, key
+}
+
+func main() {
+	m := &Map[string, int]{}
+	m.Get("key")
+	Sum[float64](1, 2)
+}
+`
+
+func TestBuildReferenceGraphGenerics(t *testing.T) {
+	referenceGraph := buildReferenceGraph(t, map[string]string{"main.go": genericsContents})
+
+	// Constraints keep their interfaces alive.
+	assert.Equal(t, []Kind{KindTypeUse}, edgeKinds(referenceGraph, "Map", "Number"))
+	assert.Equal(t, []Kind{KindTypeUse}, edgeKinds(referenceGraph, "Sum", "Number"))
+
+	// Instantiations refer to the generic declaration.
+	assert.Equal(t, []Kind{KindCompositeLit}, edgeKinds(referenceGraph, "main", "Map"))
+	assert.Equal(t, []Kind{KindCall}, edgeKinds(referenceGraph, "main", "Sum"))
+	assert.Equal(t, []Kind{KindCall}, edgeKinds(referenceGraph, "main", "Map::Get"))
+	assert.Equal(t, []Kind{KindReceiver}, edgeKinds(referenceGraph, "Map", "Map::Get"))
+
+	// Type parameters shadow declarations of the same name.
+	assert.Empty(t, edgeKinds(referenceGraph, "Map", "K"))
+	assert.Empty(t, edgeKinds(referenceGraph, "Local", "K"))
+}
+
+const kindsContents string = `package main
+
+type T struct{}
+
+func (t T) M() {}
+
+func helper() {}
+
+func main() {
+	helper()
+	var x T
+	_ = T{}
+	f := T.M
+	g := helper
+	_, _, _ = x, f, g
+}
+`
+
+// edgePositions returns the line and column of every reference between the declarations named `from` and `to`.
+func edgePositions(referenceGraph ReferenceGraph, from string, to string) [][2]int {
+	positions := [][2]int{}
+	for edge, refs := range referenceGraph.References {
+		if edge.From.Name == from && edge.To.Name == to {
+			for _, ref := range refs {
+				positions = append(positions, [2]int{ref.Pos.Line, ref.Pos.Column})
+			}
+		}
+	}
+	return positions
+}
+
+func TestBuildReferenceGraphKinds(t *testing.T) {
+	referenceGraph := buildReferenceGraph(t, map[string]string{"main.go": kindsContents})
+
+	assert.ElementsMatch(t, []Kind{KindCall, KindValueRead}, edgeKinds(referenceGraph, "main", "helper"))
+	assert.ElementsMatch(t, [][2]int{{10, 2}, {14, 7}}, edgePositions(referenceGraph, "main", "helper"))
+
+	// Method expressions also use their receiver type.
+	assert.ElementsMatch(t, []Kind{KindTypeUse, KindCompositeLit, KindTypeUse}, edgeKinds(referenceGraph, "main", "T"))
+	assert.ElementsMatch(t, [][2]int{{11, 8}, {12, 6}, {13, 7}}, edgePositions(referenceGraph, "main", "T"))
+
+	assert.Equal(t, []Kind{KindMethodExpr}, edgeKinds(referenceGraph, "main", "T::M"))
+	assert.Equal(t, [][2]int{{13, 7}}, edgePositions(referenceGraph, "main", "T::M"))
+
+	assert.Equal(t, []Kind{KindReceiver}, edgeKinds(referenceGraph, "T", "T::M"))
+	assert.Equal(t, [][2]int{{5, 1}}, edgePositions(referenceGraph, "T", "T::M"))
+}
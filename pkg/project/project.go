@@ -0,0 +1,417 @@
+package project
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/crockeo/schoner/pkg/astutil"
+	"github.com/crockeo/schoner/pkg/graph"
+	"github.com/crockeo/schoner/pkg/phases/fileinfo"
+	"github.com/crockeo/schoner/pkg/phases/interfaces"
+	"github.com/crockeo/schoner/pkg/phases/packagegraph"
+	"github.com/crockeo/schoner/pkg/phases/params"
+	"github.com/crockeo/schoner/pkg/phases/references"
+	"github.com/crockeo/schoner/pkg/rules"
+	"github.com/crockeo/schoner/pkg/set"
+	"github.com/crockeo/schoner/pkg/walk"
+)
+
+var ErrNoMatchingDeclaration = errors.New("no declaration matches symbol")
+
+// Direction chooses which references to follow from a focused declaration.
+type Direction string
+
+const (
+	// DirectionOut follows references from the focused declaration to what it references.
+	DirectionOut Direction = "out"
+	// DirectionIn follows references to the focused declaration from what references it.
+	DirectionIn Direction = "in"
+	// DirectionBoth follows references in both directions.
+	DirectionBoth Direction = "both"
+)
+
+// Loader chooses how the files of a project are found.
+type Loader string
+
+const (
+	// LoaderWalk finds every Go file beneath the project root,
+	// and infers each file's import path from its directory.
+	LoaderWalk Loader = "walk"
+	// LoaderPackages asks the go tool which files belong to which packages.
+	// It's slower, but authoritative.
+	LoaderPackages Loader = "packages"
+)
+
+// GeneratedMode chooses how declarations in generated files are treated.
+type GeneratedMode string
+
+const (
+	// GeneratedReport treats generated files like any other file.
+	GeneratedReport GeneratedMode = "report"
+	// GeneratedHide never reports declarations in generated files as unreachable,
+	// while still following their references to the rest of the project.
+	GeneratedHide GeneratedMode = "hide"
+	// GeneratedEntrypoints treats every exported declaration in a generated file as an entrypoint.
+	GeneratedEntrypoints GeneratedMode = "entrypoints"
+)
+
+type analyzeOptions struct {
+	walkOptions []walk.Option
+	loader      Loader
+	generated   GeneratedMode
+	rules       []rules.Rule
+}
+
+type Option func(*analyzeOptions)
+
+// WithWalkOptions configures which files are analyzed, and how they're read.
+func WithWalkOptions(options ...walk.Option) Option {
+	return func(ao *analyzeOptions) {
+		ao.walkOptions = append(ao.walkOptions, options...)
+	}
+}
+
+// WithLoader chooses how the files of the project are found. Defaults to LoaderWalk.
+func WithLoader(loader Loader) Option {
+	return func(ao *analyzeOptions) {
+		ao.loader = loader
+	}
+}
+
+// WithGenerated chooses how declarations in generated files are treated. Defaults to GeneratedReport.
+func WithGenerated(mode GeneratedMode) Option {
+	return func(ao *analyzeOptions) {
+		ao.generated = mode
+	}
+}
+
+// WithRules replaces the rules which recognize declarations that are used by convention,
+// like `String() string` through fmt.Stringer or provider sets built with wire.NewSet.
+// Functions, types, and values which a rule keeps are entrypoints. Defaults to rules.Builtin.
+func WithRules(conventions ...rules.Rule) Option {
+	return func(ao *analyzeOptions) {
+		ao.rules = conventions
+	}
+}
+
+func WithOptions(options ...Option) Option {
+	return func(ao *analyzeOptions) {
+		for _, option := range options {
+			option(ao)
+		}
+	}
+}
+
+// Analysis is the result of running every phase over a single project.
+type Analysis struct {
+	Root           string
+	FileInfos      map[string]*fileinfo.FileInfo
+	ReferenceGraph references.ReferenceGraph
+	PackageGraph   packagegraph.PackageGraph
+	Unreachable    set.Set[fileinfo.Declaration]
+	Entrypoints    set.Set[fileinfo.Declaration]
+	// Referrers is ReferenceGraph with every edge reversed, leading from each declaration to what references it.
+	Referrers graph.Graph[fileinfo.Declaration]
+
+	walkOptions walk.Option
+}
+
+// Analyze finds every declaration in the project at `root`,
+// and which of them can't be reached from any entrypoint.
+// `root` must be an absolute path.
+func Analyze(root string, options ...Option) (*Analysis, error) {
+	opts := analyzeOptions{loader: LoaderWalk, generated: GeneratedReport, rules: rules.Builtin}
+	WithOptions(options...)(&opts)
+	walkOptions := defaultWalkOptions(opts.walkOptions...)
+
+	var fileInfos map[string]*fileinfo.FileInfo
+	var err error
+	switch opts.loader {
+	case LoaderWalk:
+		fileInfos, err = fileinfo.FindFileInfos(root, walkOptions)
+	case LoaderPackages:
+		fileInfos, err = fileinfo.LoadFileInfos(root, walkOptions)
+	default:
+		err = fmt.Errorf("unknown loader `%s`", opts.loader)
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch opts.generated {
+	case GeneratedReport, GeneratedHide, GeneratedEntrypoints:
+	default:
+		return nil, fmt.Errorf("unknown generated mode `%s`", opts.generated)
+	}
+
+	referenceGraph, err := references.BuildReferenceGraph(root, fileInfos, walkOptions, opts.rules...)
+	if err != nil {
+		return nil, err
+	}
+
+	entrypoints := set.NewSet[fileinfo.Declaration]()
+	for decl := range referenceGraph.Graph {
+		if decl.Parent.Entrypoints.Contains(decl.Name) {
+			entrypoints.Add(decl)
+		} else if opts.generated == GeneratedEntrypoints && decl.Parent.Generated && isExported(decl.Name) {
+			entrypoints.Add(decl)
+		} else if len(referenceGraph.Conventions[decl]) > 0 && decl.Kind != fileinfo.KindMethod {
+			entrypoints.Add(decl)
+		}
+	}
+	unreachable := unreachableFrom(referenceGraph, fileInfos, entrypoints)
+	if opts.generated == GeneratedHide {
+		for _, decl := range unreachable.ToSlice() {
+			if decl.Parent.Generated {
+				unreachable.Remove(decl)
+			}
+		}
+	}
+
+	return &Analysis{
+		Root:           root,
+		FileInfos:      fileInfos,
+		ReferenceGraph: referenceGraph,
+		Referrers:      referenceGraph.Reverse(),
+		PackageGraph:   packagegraph.BuildPackageGraph(fileInfos),
+		Unreachable:    unreachable,
+		Entrypoints:    entrypoints,
+		walkOptions:    walkOptions,
+	}, nil
+}
+
+// unreachableFrom finds every declaration which can't be reached from `entrypoints`,
+// adding the init-time code of every package which is initialized to `entrypoints` first.
+//
+// A package is initialized when it contains an entrypoint, or when an initialized package imports it,
+// including imports which are only for side effects, like `import _ "pkg"`.
+// This only depends on imports, not on which declarations are reachable, just like Go's own initialization.
+func unreachableFrom(
+	referenceGraph references.ReferenceGraph,
+	fileInfos map[string]*fileinfo.FileInfo,
+	entrypoints set.Set[fileinfo.Declaration],
+) set.Set[fileinfo.Declaration] {
+	packageFiles := map[string][]*fileinfo.FileInfo{}
+	for _, fileInfo := range fileInfos {
+		packageFiles[fileInfo.ImportPath] = append(packageFiles[fileInfo.ImportPath], fileInfo)
+	}
+
+	initialized := set.NewSet[string]()
+	pending := []string{}
+	initialize := func(importPath string) {
+		if _, ok := packageFiles[importPath]; ok && initialized.Add(importPath) {
+			pending = append(pending, importPath)
+		}
+	}
+	for decl := range entrypoints {
+		initialize(decl.Parent.ImportPath)
+	}
+	for len(pending) > 0 {
+		importPath := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		for _, fileInfo := range packageFiles[importPath] {
+			for importDecl := range fileInfo.Imports {
+				initialize(importDecl.Path)
+			}
+		}
+	}
+
+	for decl := range referenceGraph.Graph {
+		if initialized.Contains(decl.Parent.ImportPath) && isInitTime(referenceGraph, decl) {
+			entrypoints.Add(decl)
+		}
+	}
+	reachable := set.NewSet[fileinfo.Declaration]()
+	_ = referenceGraph.DFS(entrypoints.ToSlice(), func(node fileinfo.Declaration) error {
+		reachable.Add(node)
+		return nil
+	})
+
+	unreachable := set.NewSet[fileinfo.Declaration]()
+	for decl := range referenceGraph.Graph {
+		if !reachable.Contains(decl) {
+			unreachable.Add(decl)
+		}
+	}
+	return unreachable
+}
+
+// isInitTime reports whether `decl` runs when its package is initialized.
+// Variables only count when their initializer calls a function declared in the project,
+// since e.g. `var ErrFoo = errors.New("foo")` has no effect if `ErrFoo` is never used.
+func isInitTime(referenceGraph references.ReferenceGraph, decl fileinfo.Declaration) bool {
+	if !decl.Parent.InitTime.Contains(decl.Name) {
+		return false
+	}
+	if decl.Kind != fileinfo.KindVar {
+		return true
+	}
+	for child := range referenceGraph.Graph[decl] {
+		for _, ref := range referenceGraph.EdgeReferences(decl, child) {
+			if ref.Kind == references.KindCall {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isExported reports whether a declaration name is exported.
+// Methods, named like `Type::Method`, are exported when the method is.
+func isExported(name string) bool {
+	parts := strings.Split(name, "::")
+	return ast.IsExported(parts[len(parts)-1])
+}
+
+// Fingerprint summarizes the name, size, and modification time of every file which Analyze would read with `options`,
+// such that the fingerprint changes whenever re-running Analyze could produce a different result.
+// With LoaderPackages it also covers files which the go tool excludes with build constraints.
+func Fingerprint(root string, options ...Option) (uint64, error) {
+	opts := analyzeOptions{}
+	WithOptions(options...)(&opts)
+	hash := fnv.New64a()
+	paths := []string{filepath.Join(root, "go.mod")}
+	err := walk.GoFiles(root, defaultWalkOptions(opts.walkOptions...), func(path string) error {
+		paths = append(paths, path)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return 0, err
+		}
+		fmt.Fprintf(hash, "%s\x00%d\x00%d\x00", path, info.Size(), info.ModTime().UnixNano())
+	}
+	return hash.Sum64(), nil
+}
+
+func defaultWalkOptions(options ...walk.Option) walk.Option {
+	// TODO: make these configurable?
+	// Like the go tool, skip vendored code and test fixtures.
+	return walk.WithOptions(
+		walk.WithIgnoreDirs(".git", "vendor", "testdata"),
+		walk.WithGitignore(true),
+		// walk.WithIgnoreTests(true),
+		walk.WithOptions(options...),
+	)
+}
+
+// FindDeclarations returns every declaration in the project which matches `symbol`.
+// A symbol may be a bare name like `Server::Start`,
+// a name qualified by its package's import path like `example.com/server.Server::Start`,
+// or a name qualified by its filename like `server/server.go::Server::Start`.
+func (a *Analysis) FindDeclarations(symbol string) ([]fileinfo.Declaration, error) {
+	found := []fileinfo.Declaration{}
+	for _, decl := range a.ReferenceGraph.SortedNodes(fileinfo.Declaration.Less) {
+		name, err := a.DeclarationName(decl)
+		if err != nil {
+			return nil, err
+		}
+		if symbol == decl.Name || symbol == name || symbol == fmt.Sprintf("%s.%s", decl.Parent.ImportPath, decl.Name) {
+			found = append(found, decl)
+		}
+	}
+	if len(found) == 0 {
+		return nil, fmt.Errorf("%w `%s`", ErrNoMatchingDeclaration, symbol)
+	}
+	return found, nil
+}
+
+// Focus returns the subgraph of declarations within `depth` references of any declaration matching `symbol`,
+// following references in the given direction.
+func (a *Analysis) Focus(symbol string, depth int, direction Direction) (references.ReferenceGraph, error) {
+	focused, err := a.FindDeclarations(symbol)
+	if err != nil {
+		return references.ReferenceGraph{}, err
+	}
+	neighborhood := set.NewSet[fileinfo.Declaration]()
+	if direction == DirectionOut || direction == DirectionBoth {
+		neighborhood.UnionInPlace(a.ReferenceGraph.WithinDistance(focused, depth))
+	}
+	if direction == DirectionIn || direction == DirectionBoth {
+		neighborhood.UnionInPlace(a.Referrers.WithinDistance(focused, depth))
+	}
+	return a.ReferenceGraph.Subgraph(neighborhood), nil
+}
+
+// DeclarationAt returns the innermost declaration in `filename` which contains `offset`.
+func (a *Analysis) DeclarationAt(filename string, offset int) (fileinfo.Declaration, bool) {
+	fileInfo, ok := a.FileInfos[filename]
+	if !ok {
+		return fileinfo.Declaration{}, false
+	}
+	var found fileinfo.Declaration
+	ok = false
+	for _, decl := range fileInfo.Declarations {
+		if offset < decl.Pos.Offset || offset > decl.End.Offset {
+			continue
+		}
+		if !ok || decl.Pos.Offset > found.Pos.Offset || (decl.Pos.Offset == found.Pos.Offset && decl.Name < found.Name) {
+			found = decl
+			ok = true
+		}
+	}
+	return found, ok
+}
+
+// UnusedParams finds the parameters of reachable functions which are never read,
+// and the results of reachable functions which every caller discards.
+func (a *Analysis) UnusedParams() ([]params.Finding, error) {
+	return params.FindUnused(a.FileInfos, a.ReferenceGraph, a.Referrers, a.Unreachable, a.walkOptions)
+}
+
+// InterfaceMethods explains why each reachable method is reachable,
+// and in particular whether methods which are never referenced directly implement an interface which is used.
+func (a *Analysis) InterfaceMethods() []interfaces.Method {
+	return interfaces.ClassifyMethods(a.FileInfos, a.ReferenceGraph, a.Referrers, a.Unreachable)
+}
+
+// ReachabilityPath returns the shortest chain of references from an entrypoint to `decl`,
+// or nil if `decl` is unreachable.
+func (a *Analysis) ReachabilityPath(decl fileinfo.Declaration) []fileinfo.Declaration {
+	entrypoints := a.Entrypoints.Sorted(fileinfo.Declaration.Less)
+	return a.ReferenceGraph.ShortestPath(entrypoints, decl, fileinfo.Declaration.Less)
+}
+
+// DeclarationNames returns the sorted names of `decls`,
+// qualified by their filenames relative to the project root.
+func (a *Analysis) DeclarationNames(decls []fileinfo.Declaration) ([]string, error) {
+	names := make([]string, 0, len(decls))
+	for _, decl := range decls {
+		name, err := a.DeclarationName(decl)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// DeclarationName returns the name of `decl`,
+// qualified by its filename relative to the project root.
+func (a *Analysis) DeclarationName(decl fileinfo.Declaration) (string, error) {
+	filename, err := a.RelativePath(decl.Parent.Filename)
+	if err != nil {
+		return "", err
+	}
+	return astutil.Qualify(filename, decl.Name), nil
+}
+
+// RelativePath returns `filename` relative to the project root.
+func (a *Analysis) RelativePath(filename string) (string, error) {
+	if !strings.HasPrefix(filename, a.Root) {
+		return "", fmt.Errorf("file %s does not begin with expected path %s", filename, a.Root)
+	}
+	filename = filename[len(a.Root):]
+	filename = strings.TrimPrefix(filename, "/")
+	return filename, nil
+}
@@ -0,0 +1,242 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/crockeo/schoner/internal/testproject"
+	"github.com/crockeo/schoner/pkg/phases/fileinfo"
+	"github.com/crockeo/schoner/pkg/rules"
+	"github.com/crockeo/schoner/pkg/walk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const generatedContents string = `// Code generated by protoc-gen-go. DO NOT EDIT.
+
+package main
+
+type Request struct{}
+
+func (r *Request) GetName() string { return "" }
+
+func Register() { handle() }
+
+func unexported() {}
+`
+
+const handlerContents string = `package main
+
+func main() {}
+
+func handle() {}
+
+func dead() {}
+`
+
+func unreachableNames(t *testing.T, files map[string]string, options ...Option) []string {
+	root := testproject.Write(t, files)
+	analysis, err := Analyze(root, options...)
+	require.NoError(t, err)
+	names := []string{}
+	for decl := range analysis.Unreachable {
+		names = append(names, decl.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestAnalyzeGenerated(t *testing.T) {
+	files := map[string]string{
+		"service.pb.go": generatedContents,
+		"main.go":       handlerContents,
+	}
+	assert.Equal(
+		t,
+		[]string{"Register", "Request", "Request::GetName", "dead", "handle", "unexported"},
+		unreachableNames(t, files),
+	)
+	assert.Equal(t, []string{"dead", "handle"}, unreachableNames(t, files, WithGenerated(GeneratedHide)))
+	assert.Equal(t, []string{"dead", "unexported"}, unreachableNames(t, files, WithGenerated(GeneratedEntrypoints)))
+}
+
+const driverContents string = `package driver
+
+func init() { register() }
+
+func register() {}
+`
+
+func TestAnalyzeBlankImport(t *testing.T) {
+	// The driver is only registered when the package which blank-imports it is initialized.
+	files := map[string]string{
+		"main.go":          "package main\n\nimport \"example.com/root/db\"\n\nfunc main() { db.Open() }\n",
+		"db/db.go":         "package db\n\nimport _ \"example.com/root/driver\"\n\nfunc Open() {}\n",
+		"driver/driver.go": driverContents,
+	}
+	assert.Equal(t, []string{}, unreachableNames(t, files))
+
+	files["main.go"] = "package main\n\nfunc main() {}\n"
+	assert.Equal(t, []string{"Open", "init", "register"}, unreachableNames(t, files))
+
+	// Blank imports run as soon as the importing package is initialized,
+	// even from a file which declares nothing.
+	files = map[string]string{
+		"main.go":          "package main\n\nfunc main() {}\n",
+		"imports.go":       "package main\n\nimport _ \"example.com/root/driver\"\n",
+		"driver/driver.go": driverContents,
+	}
+	assert.Equal(t, []string{}, unreachableNames(t, files))
+}
+
+const pluginContents string = `package plugin
+
+import "errors"
+
+var ErrUnused = errors.New("unused")
+
+var registry = newRegistry()
+
+func newRegistry() map[string]int { return map[string]int{} }
+
+func init() { setup() }
+
+func setup() {}
+`
+
+func TestAnalyzeInitTime(t *testing.T) {
+	// Init-time code only runs in packages which are imported from an entrypoint.
+	files := map[string]string{
+		"main.go":          "package main\n\nimport _ \"example.com/root/plugin\"\n\nfunc main() {}\n",
+		"plugin/plugin.go": pluginContents,
+	}
+	assert.Equal(t, []string{"ErrUnused"}, unreachableNames(t, files))
+
+	files["main.go"] = "package main\n\nfunc main() {}\n"
+	assert.Equal(
+		t,
+		[]string{"ErrUnused", "init", "newRegistry", "registry", "setup"},
+		unreachableNames(t, files),
+	)
+
+	// Initialization follows imports transitively, even through packages whose declarations are all unreachable,
+	// but never reaches packages which are only imported by packages that aren't initialized.
+	files = map[string]string{
+		"main.go":          "package main\n\nimport _ \"example.com/root/app\"\n\nfunc main() {}\n",
+		"app/app.go":       "package app\n\nimport \"example.com/root/plugin\"\n\nfunc Unused() { plugin.Nothing() }\n",
+		"plugin/plugin.go": pluginContents + "\nfunc Nothing() {}\n",
+		"tools/tools.go":   "package tools\n\nimport _ \"example.com/root/driver\"\n",
+		"driver/driver.go": driverContents,
+	}
+	assert.Equal(
+		t,
+		[]string{"ErrUnused", "Nothing", "Unused", "init", "register"},
+		unreachableNames(t, files),
+	)
+}
+
+const linknameContents string = `package main
+
+import _ "unsafe"
+
+func main() { now() }
+
+//go:linkname now example.com/root/clock.(*clock).now
+func now() int64
+
+//go:linkname unused example.com/root/clock.unused
+func unused()
+`
+
+const clockContents string = `package clock
+
+type clock struct{}
+
+func (c *clock) now() int64 { return 0 }
+
+func unused() {}
+
+//export Tick
+func Tick() {}
+`
+
+func TestAnalyzeDirectives(t *testing.T) {
+	files := map[string]string{
+		"main.go":        linknameContents,
+		"clock/clock.go": clockContents,
+	}
+	assert.Equal(t, []string{"clock", "unused", "unused"}, unreachableNames(t, files))
+}
+
+func TestFingerprint(t *testing.T) {
+	root := testproject.Write(t, map[string]string{
+		"main.go":    "package main\n",
+		"gen/gen.go": "package gen\n",
+	})
+	mainPath := filepath.Join(root, "main.go")
+	genPath := filepath.Join(root, "gen", "gen.go")
+
+	options := WithWalkOptions(walk.WithExclude("gen/**"))
+	before, err := Fingerprint(root, options)
+	require.NoError(t, err)
+
+	// Files which Analyze wouldn't read don't change the fingerprint.
+	require.NoError(t, os.WriteFile(genPath, []byte("package gen\n\nfunc Gen() {}\n"), 0o644))
+	after, err := Fingerprint(root, options)
+	require.NoError(t, err)
+	assert.Equal(t, before, after)
+
+	require.NoError(t, os.WriteFile(mainPath, []byte("package main\n\nfunc main() {}\n"), 0o644))
+	after, err = Fingerprint(root, options)
+	require.NoError(t, err)
+	assert.NotEqual(t, before, after)
+}
+
+const wireContents string = `package app
+
+import "github.com/google/wire"
+
+var ProviderSet = wire.NewSet(NewStore, NewServer)
+
+var config = wire.Value(1)
+
+func NewStore() int { return 0 }
+
+func NewServer(store int) string { return "" }
+
+func HandleIndex() {}
+`
+
+// handlerRule keeps functions named like HTTP handlers, which a hypothetical router finds through reflection.
+type handlerRule struct{}
+
+func (handlerRule) Name() string {
+	return "handler"
+}
+
+func (handlerRule) Keeps(decl rules.Declaration) bool {
+	return decl.Kind == fileinfo.KindFunc && strings.HasPrefix(decl.Name, "Handle")
+}
+
+func TestAnalyzeRules(t *testing.T) {
+	files := map[string]string{
+		"main.go":     "package main\n\nimport \"example.com/root/app\"\n\nfunc main() { app.Run() }\n",
+		"app/app.go":  "package app\n\nfunc Run() {}\n",
+		"app/wire.go": wireContents,
+	}
+	// Only wire's code generator reads provider sets, so they're kept along with the providers they reference.
+	assert.Equal(t, []string{"HandleIndex", "config"}, unreachableNames(t, files))
+	assert.Equal(
+		t,
+		[]string{"HandleIndex", "NewServer", "NewStore", "ProviderSet", "config"},
+		unreachableNames(t, files, WithRules()),
+	)
+	assert.Equal(
+		t,
+		[]string{"NewServer", "NewStore", "ProviderSet", "config"},
+		unreachableNames(t, files, WithRules(handlerRule{})),
+	)
+}
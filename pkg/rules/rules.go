@@ -0,0 +1,204 @@
+package rules
+
+import (
+	"go/ast"
+	"go/types"
+	"strings"
+
+	"github.com/crockeo/schoner/pkg/astutil"
+	"github.com/crockeo/schoner/pkg/phases/fileinfo"
+)
+
+// Declaration describes a declaration for rules to match against.
+type Declaration struct {
+	fileinfo.Declaration
+	// Node declares the declaration: an *ast.FuncDecl for functions and methods,
+	// an *ast.TypeSpec for types, or an *ast.ValueSpec for variables and constants,
+	// which may declare several names at once.
+	Node ast.Node
+}
+
+// Rule recognizes declarations which are used by convention rather than being referenced directly,
+// e.g. by a framework through an interface it declares, through reflection, or by a code generator.
+//
+// Functions, types, variables, and constants which a rule keeps are entrypoints.
+// Methods which a rule keeps are referenced by their receiver type with references.KindConvention,
+// which explains why they're reachable, since every method is already reachable through its receiver type.
+type Rule interface {
+	// Name identifies the rule when explaining why a declaration is reachable.
+	Name() string
+	// Keeps reports whether `decl` is used by convention.
+	Keeps(decl Declaration) bool
+}
+
+// Method is the name and signature of a method.
+type Method struct {
+	// Name is the name of the method, without its receiver.
+	Name string
+	// Params and Results are the types of the method's parameters and results, as written,
+	// with one entry per parameter or result, e.g. `[]string{"int", "int"}` for `(i, j int)`.
+	Params  []string
+	Results []string
+}
+
+// Method describes `d` as a method, or fails if it isn't one.
+func (d Declaration) Method() (Method, bool) {
+	funcDecl, ok := d.Node.(*ast.FuncDecl)
+	if !ok || funcDecl.Recv == nil {
+		return Method{}, false
+	}
+	return Method{
+		Name:    funcDecl.Name.Name,
+		Params:  fieldTypes(funcDecl.Type.Params),
+		Results: fieldTypes(funcDecl.Type.Results),
+	}, true
+}
+
+func fieldTypes(fields *ast.FieldList) []string {
+	fieldTypes := []string{}
+	if fields == nil {
+		return fieldTypes
+	}
+	for _, field := range fields.List {
+		fieldType := normalizeType(types.ExprString(field.Type))
+		count := len(field.Names)
+		if count == 0 {
+			count = 1
+		}
+		for i := 0; i < count; i++ {
+			fieldTypes = append(fieldTypes, fieldType)
+		}
+	}
+	return fieldTypes
+}
+
+func normalizeType(fieldType string) string {
+	return strings.ReplaceAll(fieldType, "interface{}", "any")
+}
+
+// MethodRule keeps methods with a particular name and signature.
+// Types are compared as written, so `fmt.State` won't match a package imported under another name.
+type MethodRule struct {
+	RuleName string
+	Method   string
+	Params   []string
+	Results  []string
+}
+
+func (mr MethodRule) Name() string {
+	return mr.RuleName
+}
+
+func (mr MethodRule) Keeps(decl Declaration) bool {
+	method, ok := decl.Method()
+	return ok && method.Name == mr.Method && equalTypes(method.Params, mr.Params) && equalTypes(method.Results, mr.Results)
+}
+
+func equalTypes(actual []string, expected []string) bool {
+	if len(actual) != len(expected) {
+		return false
+	}
+	for i := range actual {
+		if actual[i] != expected[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// CallRule keeps variables whose initializer calls a particular function from another package,
+// like the provider sets built with wire.NewSet, which only wire's code generator reads.
+type CallRule struct {
+	RuleName string
+	// Package is the import path of the package which declares the function, and Func is its name.
+	Package string
+	Func    string
+}
+
+func (cr CallRule) Name() string {
+	return cr.RuleName
+}
+
+func (cr CallRule) Keeps(decl Declaration) bool {
+	spec, ok := decl.Node.(*ast.ValueSpec)
+	if !ok || decl.Kind != fileinfo.KindVar {
+		return false
+	}
+	values := spec.Values
+	if len(spec.Names) > 1 && len(spec.Values) == len(spec.Names) {
+		for i, name := range spec.Names {
+			if name.Name == decl.Name {
+				values = spec.Values[i : i+1]
+			}
+		}
+	}
+	for _, value := range values {
+		call, ok := value.(*ast.CallExpr)
+		if !ok {
+			continue
+		}
+		if selector, ok := call.Fun.(*ast.SelectorExpr); ok && selector.Sel.Name == cr.Func && cr.isPackage(decl.Parent, selector.X) {
+			return true
+		}
+	}
+	return false
+}
+
+// isPackage reports whether `expr` names the package which declares the function.
+func (cr CallRule) isPackage(fileInfo *fileinfo.FileInfo, expr ast.Expr) bool {
+	name, ok := astutil.ExprName(expr)
+	if !ok {
+		return false
+	}
+	for importDecl := range fileInfo.Imports {
+		if importDecl.Name == name && importDecl.Path == cr.Package {
+			return true
+		}
+	}
+	return false
+}
+
+// Standard contains a rule for every interface in the standard library
+// whose methods are commonly called by the standard library itself,
+// e.g. `String() string` through fmt.Stringer or `MarshalJSON() ([]byte, error)` through json.Marshaler.
+var Standard = []Rule{
+	MethodRule{RuleName: "fmt.Stringer", Method: "String", Results: []string{"string"}},
+	MethodRule{RuleName: "fmt.GoStringer", Method: "GoString", Results: []string{"string"}},
+	MethodRule{RuleName: "fmt.Formatter", Method: "Format", Params: []string{"fmt.State", "rune"}},
+	MethodRule{RuleName: "error", Method: "Error", Results: []string{"string"}},
+	MethodRule{RuleName: "errors.Unwrap", Method: "Unwrap", Results: []string{"error"}},
+	MethodRule{RuleName: "errors.Unwrap", Method: "Unwrap", Results: []string{"[]error"}},
+	MethodRule{RuleName: "errors.Is", Method: "Is", Params: []string{"error"}, Results: []string{"bool"}},
+	MethodRule{RuleName: "errors.As", Method: "As", Params: []string{"any"}, Results: []string{"bool"}},
+	MethodRule{RuleName: "json.Marshaler", Method: "MarshalJSON", Results: []string{"[]byte", "error"}},
+	MethodRule{RuleName: "json.Unmarshaler", Method: "UnmarshalJSON", Params: []string{"[]byte"}, Results: []string{"error"}},
+	MethodRule{RuleName: "xml.Marshaler", Method: "MarshalXML", Params: []string{"*xml.Encoder", "xml.StartElement"}, Results: []string{"error"}},
+	MethodRule{RuleName: "xml.Unmarshaler", Method: "UnmarshalXML", Params: []string{"*xml.Decoder", "xml.StartElement"}, Results: []string{"error"}},
+	MethodRule{RuleName: "encoding.TextMarshaler", Method: "MarshalText", Results: []string{"[]byte", "error"}},
+	MethodRule{RuleName: "encoding.TextUnmarshaler", Method: "UnmarshalText", Params: []string{"[]byte"}, Results: []string{"error"}},
+	MethodRule{RuleName: "encoding.BinaryMarshaler", Method: "MarshalBinary", Results: []string{"[]byte", "error"}},
+	MethodRule{RuleName: "encoding.BinaryUnmarshaler", Method: "UnmarshalBinary", Params: []string{"[]byte"}, Results: []string{"error"}},
+	MethodRule{RuleName: "sort.Interface", Method: "Len", Results: []string{"int"}},
+	MethodRule{RuleName: "sort.Interface", Method: "Less", Params: []string{"int", "int"}, Results: []string{"bool"}},
+	MethodRule{RuleName: "sort.Interface", Method: "Swap", Params: []string{"int", "int"}},
+	MethodRule{RuleName: "heap.Interface", Method: "Push", Params: []string{"any"}},
+	MethodRule{RuleName: "heap.Interface", Method: "Pop", Results: []string{"any"}},
+	MethodRule{RuleName: "http.Handler", Method: "ServeHTTP", Params: []string{"http.ResponseWriter", "*http.Request"}},
+	MethodRule{RuleName: "io.Reader", Method: "Read", Params: []string{"[]byte"}, Results: []string{"int", "error"}},
+	MethodRule{RuleName: "io.Writer", Method: "Write", Params: []string{"[]byte"}, Results: []string{"int", "error"}},
+	MethodRule{RuleName: "io.Closer", Method: "Close", Results: []string{"error"}},
+	MethodRule{RuleName: "flag.Value", Method: "Set", Params: []string{"string"}, Results: []string{"error"}},
+	MethodRule{RuleName: "sql.Scanner", Method: "Scan", Params: []string{"any"}, Results: []string{"error"}},
+	MethodRule{RuleName: "driver.Valuer", Method: "Value", Results: []string{"driver.Value", "error"}},
+}
+
+// Frameworks contains rules for declarations which popular frameworks and code generators use without Go code referencing them.
+//
+// Functions assigned to fields, like the RunE hook of a cobra.Command, need no rule,
+// since the literal which assigns them already references them.
+var Frameworks = []Rule{
+	CallRule{RuleName: "wire.NewSet", Package: "github.com/google/wire", Func: "NewSet"},
+}
+
+// Builtin contains every rule in Standard and Frameworks.
+var Builtin = append(append([]Rule{}, Standard...), Frameworks...)
@@ -0,0 +1,52 @@
+package rules
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/crockeo/schoner/pkg/astutil"
+	"github.com/crockeo/schoner/pkg/phases/fileinfo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const methodsContents string = `package main
+
+func (t T) String() string { return "" }
+func (t T) Format(f fmt.State, verb rune) {}
+func (t T) Less(i, j int) bool { return false }
+func (t T) As(target interface{}) bool { return false }
+func (t T) Len(extra int) int { return 0 }
+func (t T) MarshalJSON() (string, error) { return "", nil }
+func (t T) Describe() string { return "" }
+func String() string { return "" }
+`
+
+func TestStandard(t *testing.T) {
+	fileAst, err := parser.ParseFile(token.NewFileSet(), "main.go", methodsContents, 0)
+	require.NoError(t, err)
+
+	kept := map[string][]string{}
+	for _, decl := range fileAst.Decls {
+		funcDecl := decl.(*ast.FuncDecl)
+		name, err := astutil.FunctionName(funcDecl)
+		require.NoError(t, err)
+		for _, rule := range Standard {
+			if rule.Keeps(Declaration{Declaration: fileinfo.Declaration{Name: name}, Node: funcDecl}) {
+				kept[name] = append(kept[name], rule.Name())
+			}
+		}
+	}
+	assert.Equal(
+		t,
+		map[string][]string{
+			"T::String": {"fmt.Stringer"},
+			"T::Format": {"fmt.Formatter"},
+			"T::Less":   {"sort.Interface"},
+			"T::As":     {"errors.As"},
+		},
+		kept,
+	)
+}
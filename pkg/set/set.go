@@ -1,5 +1,7 @@
 package set
 
+import "sort"
+
 type Set[T comparable] map[T]struct{}
 
 func NewSet[T comparable](elements ...T) Set[T] {
@@ -52,3 +54,13 @@ func (s Set[T]) ToSlice() []T {
 	}
 	return slice
 }
+
+// Sorted returns the elements of the set in the order given by `less`,
+// which reports whether its first argument belongs before its second.
+func (s Set[T]) Sorted(less func(T, T) bool) []T {
+	slice := s.ToSlice()
+	sort.Slice(slice, func(i, j int) bool {
+		return less(slice[i], slice[j])
+	})
+	return slice
+}
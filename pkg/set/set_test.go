@@ -59,3 +59,9 @@ func TestSet_ToSlice(t *testing.T) {
 	sort.Strings(slice)
 	assert.Equal(t, []string{"v1", "v2"}, slice)
 }
+
+func TestSet_Sorted(t *testing.T) {
+	set := NewSet("b", "c", "a")
+	assert.Equal(t, []string{"a", "b", "c"}, set.Sorted(func(a, b string) bool { return a < b }))
+	assert.Equal(t, []string{"c", "b", "a"}, set.Sorted(func(a, b string) bool { return a > b }))
+}
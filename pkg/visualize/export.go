@@ -0,0 +1,412 @@
+package visualize
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/crockeo/schoner/pkg/astutil"
+	"github.com/crockeo/schoner/pkg/phases/fileinfo"
+	"github.com/crockeo/schoner/pkg/phases/references"
+	"github.com/crockeo/schoner/pkg/set"
+)
+
+// Format is a textual graph format which can be exported without graphviz.
+type Format string
+
+const (
+	FormatDOT     Format = "dot"
+	FormatGraphML Format = "graphml"
+	FormatMermaid Format = "mermaid"
+	FormatJSON    Format = "json"
+)
+
+// Export writes the reference graph to `w` in the provided format.
+// Unlike Visualize, none of these formats require cgo.
+func Export(
+	w io.Writer,
+	format Format,
+	graph references.ReferenceGraph,
+	entrypoints set.Set[fileinfo.Declaration],
+	unreachable set.Set[fileinfo.Declaration],
+	options ...Option,
+) error {
+	opts := visualizeOptions{}
+	WithOptions(options...)(&opts)
+	if opts.collapsePackages && format != FormatDOT {
+		return fmt.Errorf("collapsing packages is not supported by the %s format", format)
+	}
+
+	switch format {
+	case FormatDOT:
+		if opts.collapsePackages {
+			return writePackagesDOT(w, graph, entrypoints, unreachable)
+		}
+		return writeDOT(w, graph, entrypoints, unreachable)
+	case FormatGraphML:
+		return writeGraphML(w, graph, entrypoints, unreachable)
+	case FormatMermaid:
+		return writeMermaid(w, graph, entrypoints, unreachable)
+	case FormatJSON:
+		return writeJSON(w, graph, entrypoints, unreachable)
+	default:
+		return fmt.Errorf("unknown export format `%s`", format)
+	}
+}
+
+// nodeID returns a stable identifier for a declaration's node,
+// matching the one used by Visualize.
+func nodeID(decl fileinfo.Declaration) string {
+	return astutil.Qualify(decl.Parent.Filename, decl.Name)
+}
+
+// dotQuote quotes `s` as a DOT string literal.
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
+
+// writeDOT writes the graph in graphviz's DOT language,
+// mirroring the clusters and styles produced by Visualize.
+func writeDOT(
+	w io.Writer,
+	graph references.ReferenceGraph,
+	entrypoints set.Set[fileinfo.Declaration],
+	unreachable set.Set[fileinfo.Declaration],
+) error {
+	decls := graph.SortedNodes(fileinfo.Declaration.Less)
+
+	// Declarations are sorted by filename,
+	// so each file's declarations are contiguous, but each package's files may not be.
+	packagePaths := []string{}
+	filesByPackage := map[string][]string{}
+	declsByFile := map[string][]fileinfo.Declaration{}
+	for _, decl := range decls {
+		importPath := decl.Parent.ImportPath
+		filename := decl.Parent.Filename
+		if _, ok := filesByPackage[importPath]; !ok {
+			packagePaths = append(packagePaths, importPath)
+		}
+		if _, ok := declsByFile[filename]; !ok {
+			filesByPackage[importPath] = append(filesByPackage[importPath], filename)
+		}
+		declsByFile[filename] = append(declsByFile[filename], decl)
+	}
+
+	b := &strings.Builder{}
+	b.WriteString("digraph {\n")
+	for _, importPath := range packagePaths {
+		fmt.Fprintf(b, "\tsubgraph %s {\n", dotQuote("cluster_"+importPath))
+		fmt.Fprintf(b, "\t\tlabel=%s;\n\t\tstyle=rounded;\n", dotQuote(importPath))
+		for _, filename := range filesByPackage[importPath] {
+			fmt.Fprintf(b, "\t\tsubgraph %s {\n", dotQuote("cluster_"+filename))
+			fmt.Fprintf(b, "\t\t\tlabel=%s;\n\t\t\tstyle=dashed;\n", dotQuote(filepath.Base(filename)))
+			for _, decl := range declsByFile[filename] {
+				attributes := []string{fmt.Sprintf("label=%s", dotQuote(decl.Name))}
+				if fillColor := declarationFillColor(decl, entrypoints, unreachable); fillColor != "" {
+					attributes = append(attributes, "style=filled", fmt.Sprintf("fillcolor=%s", fillColor))
+				}
+				fmt.Fprintf(b, "\t\t\t%s [%s];\n", dotQuote(nodeID(decl)), strings.Join(attributes, ", "))
+			}
+			b.WriteString("\t\t}\n")
+		}
+		b.WriteString("\t}\n")
+	}
+	for _, decl := range decls {
+		for _, peer := range graph.SortedChildren(decl, fileinfo.Declaration.Less) {
+			fmt.Fprintf(
+				b,
+				"\t%s -> %s [tooltip=%s];\n",
+				dotQuote(nodeID(decl)),
+				dotQuote(nodeID(peer)),
+				dotQuote(edgeTooltip(graph.EdgeReferences(decl, peer))),
+			)
+		}
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writePackagesDOT writes the graph collapsed into packages in graphviz's DOT language,
+// mirroring Visualize with WithCollapsePackages.
+func writePackagesDOT(
+	w io.Writer,
+	graph references.ReferenceGraph,
+	entrypoints set.Set[fileinfo.Declaration],
+	unreachable set.Set[fileinfo.Declaration],
+) error {
+	packageNodes, packageEdges := collapsePackages(graph, entrypoints, unreachable)
+
+	b := &strings.Builder{}
+	b.WriteString("digraph {\n")
+	for _, packageNode := range packageNodes {
+		label := fmt.Sprintf(
+			"%s\n%d declarations, %d unreachable",
+			packageNode.ImportPath,
+			packageNode.Declarations,
+			packageNode.Unreachable,
+		)
+		attributes := []string{"shape=box", fmt.Sprintf("label=%s", dotQuote(label))}
+		if fillColor := packageFillColor(packageNode); fillColor != "" {
+			attributes = append(attributes, "style=filled", fmt.Sprintf("fillcolor=%s", fillColor))
+		}
+		fmt.Fprintf(b, "\t%s [%s];\n", dotQuote(packageNode.ImportPath), strings.Join(attributes, ", "))
+	}
+	for _, packageEdge := range packageEdges {
+		fmt.Fprintf(
+			b,
+			"\t%s -> %s [label=\"%d\"];\n",
+			dotQuote(packageEdge.From),
+			dotQuote(packageEdge.To),
+			packageEdge.References,
+		)
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+type graphML struct {
+	XMLName xml.Name     `xml:"graphml"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID       string `xml:"id,attr"`
+	For      string `xml:"for,attr"`
+	AttrName string `xml:"attr.name,attr"`
+	AttrType string `xml:"attr.type,attr"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// writeGraphML writes the graph as GraphML,
+// with the properties of each declaration and reference stored as data attributes.
+func writeGraphML(
+	w io.Writer,
+	graph references.ReferenceGraph,
+	entrypoints set.Set[fileinfo.Declaration],
+	unreachable set.Set[fileinfo.Declaration],
+) error {
+	document := graphML{
+		Xmlns: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphMLKey{
+			{ID: "name", For: "node", AttrName: "name", AttrType: "string"},
+			{ID: "kind", For: "node", AttrName: "kind", AttrType: "string"},
+			{ID: "package", For: "node", AttrName: "package", AttrType: "string"},
+			{ID: "file", For: "node", AttrName: "file", AttrType: "string"},
+			{ID: "line", For: "node", AttrName: "line", AttrType: "int"},
+			{ID: "entrypoint", For: "node", AttrName: "entrypoint", AttrType: "boolean"},
+			{ID: "unreachable", For: "node", AttrName: "unreachable", AttrType: "boolean"},
+			{ID: "references", For: "edge", AttrName: "references", AttrType: "string"},
+		},
+		Graph: graphMLGraph{
+			ID:          "references",
+			EdgeDefault: "directed",
+		},
+	}
+
+	decls := graph.SortedNodes(fileinfo.Declaration.Less)
+	for _, decl := range decls {
+		document.Graph.Nodes = append(document.Graph.Nodes, graphMLNode{
+			ID: nodeID(decl),
+			Data: []graphMLData{
+				{Key: "name", Value: decl.Name},
+				{Key: "kind", Value: decl.Kind.String()},
+				{Key: "package", Value: decl.Parent.ImportPath},
+				{Key: "file", Value: decl.Parent.Filename},
+				{Key: "line", Value: fmt.Sprintf("%d", decl.Pos.Line)},
+				{Key: "entrypoint", Value: fmt.Sprintf("%t", entrypoints.Contains(decl))},
+				{Key: "unreachable", Value: fmt.Sprintf("%t", unreachable.Contains(decl))},
+			},
+		})
+	}
+	for _, decl := range decls {
+		for _, peer := range graph.SortedChildren(decl, fileinfo.Declaration.Less) {
+			document.Graph.Edges = append(document.Graph.Edges, graphMLEdge{
+				Source: nodeID(decl),
+				Target: nodeID(peer),
+				Data: []graphMLData{
+					{Key: "references", Value: edgeTooltip(graph.EdgeReferences(decl, peer))},
+				},
+			})
+		}
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// mermaidQuote quotes `s` as a Mermaid label,
+// which doesn't support escaping quotes with backslashes.
+func mermaidQuote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "#quot;") + `"`
+}
+
+// writeMermaid writes the graph as a Mermaid flowchart,
+// with one subgraph per package.
+func writeMermaid(
+	w io.Writer,
+	graph references.ReferenceGraph,
+	entrypoints set.Set[fileinfo.Declaration],
+	unreachable set.Set[fileinfo.Declaration],
+) error {
+	decls := graph.SortedNodes(fileinfo.Declaration.Less)
+
+	// Mermaid IDs can't contain most punctuation, so nodes and subgraphs are numbered instead.
+	ids := map[fileinfo.Declaration]string{}
+	packagePaths := []string{}
+	declsByPackage := map[string][]fileinfo.Declaration{}
+	for i, decl := range decls {
+		ids[decl] = fmt.Sprintf("n%d", i)
+		importPath := decl.Parent.ImportPath
+		if _, ok := declsByPackage[importPath]; !ok {
+			packagePaths = append(packagePaths, importPath)
+		}
+		declsByPackage[importPath] = append(declsByPackage[importPath], decl)
+	}
+
+	b := &strings.Builder{}
+	b.WriteString("flowchart LR\n")
+	b.WriteString("\tclassDef entrypoint fill:chartreuse\n")
+	b.WriteString("\tclassDef unreachable fill:gray\n")
+	for i, importPath := range packagePaths {
+		fmt.Fprintf(b, "\tsubgraph p%d [%s]\n", i, mermaidQuote(importPath))
+		for _, decl := range declsByPackage[importPath] {
+			class := ""
+			if entrypoints.Contains(decl) {
+				class = ":::entrypoint"
+			} else if unreachable.Contains(decl) {
+				class = ":::unreachable"
+			}
+			fmt.Fprintf(b, "\t\t%s[%s]%s\n", ids[decl], mermaidQuote(decl.Name), class)
+		}
+		b.WriteString("\tend\n")
+	}
+	for _, decl := range decls {
+		for _, peer := range graph.SortedChildren(decl, fileinfo.Declaration.Less) {
+			fmt.Fprintf(b, "\t%s --> %s\n", ids[decl], ids[peer])
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+type jsonGraph struct {
+	Nodes []jsonNode `json:"nodes"`
+	Edges []jsonEdge `json:"edges"`
+}
+
+type jsonNode struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Kind        string `json:"kind"`
+	Package     string `json:"package"`
+	File        string `json:"file"`
+	Line        int    `json:"line"`
+	Entrypoint  bool   `json:"entrypoint"`
+	Unreachable bool   `json:"unreachable"`
+}
+
+type jsonEdge struct {
+	From       string          `json:"from"`
+	To         string          `json:"to"`
+	References []jsonReference `json:"references"`
+}
+
+type jsonReference struct {
+	Kind   string `json:"kind"`
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+// writeJSON writes the graph as lists of nodes and edges,
+// where each edge refers to its endpoints by their node IDs.
+func writeJSON(
+	w io.Writer,
+	graph references.ReferenceGraph,
+	entrypoints set.Set[fileinfo.Declaration],
+	unreachable set.Set[fileinfo.Declaration],
+) error {
+	document := jsonGraph{
+		Nodes: []jsonNode{},
+		Edges: []jsonEdge{},
+	}
+
+	decls := graph.SortedNodes(fileinfo.Declaration.Less)
+	for _, decl := range decls {
+		document.Nodes = append(document.Nodes, jsonNode{
+			ID:          nodeID(decl),
+			Name:        decl.Name,
+			Kind:        decl.Kind.String(),
+			Package:     decl.Parent.ImportPath,
+			File:        decl.Parent.Filename,
+			Line:        decl.Pos.Line,
+			Entrypoint:  entrypoints.Contains(decl),
+			Unreachable: unreachable.Contains(decl),
+		})
+	}
+	for _, decl := range decls {
+		for _, peer := range graph.SortedChildren(decl, fileinfo.Declaration.Less) {
+			refs := []jsonReference{}
+			for _, ref := range graph.EdgeReferences(decl, peer) {
+				refs = append(refs, jsonReference{
+					Kind:   ref.Kind.String(),
+					File:   ref.Pos.Filename,
+					Line:   ref.Pos.Line,
+					Column: ref.Pos.Column,
+				})
+			}
+			document.Edges = append(document.Edges, jsonEdge{
+				From:       nodeID(decl),
+				To:         nodeID(peer),
+				References: refs,
+			})
+		}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(document)
+}
@@ -0,0 +1,91 @@
+package visualize
+
+import (
+	"bytes"
+	"flag"
+	"go/token"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/crockeo/schoner/pkg/phases/fileinfo"
+	"github.com/crockeo/schoner/pkg/phases/references"
+	"github.com/crockeo/schoner/pkg/set"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func TestDOTQuote(t *testing.T) {
+	assert.Equal(t, `"plain"`, dotQuote("plain"))
+	assert.Equal(t, `"say \"hi\""`, dotQuote(`say "hi"`))
+	assert.Equal(t, `"C:\\path"`, dotQuote(`C:\path`))
+	assert.Equal(t, `"first\nsecond"`, dotQuote("first\nsecond"))
+}
+
+func TestMermaidQuote(t *testing.T) {
+	assert.Equal(t, `"plain"`, mermaidQuote("plain"))
+	assert.Equal(t, `"say #quot;hi#quot;"`, mermaidQuote(`say "hi"`))
+}
+
+// exportGraph builds a small graph spanning two packages,
+// with an entrypoint, an unreachable declaration, and a name which needs escaping.
+func exportGraph() (references.ReferenceGraph, set.Set[fileinfo.Declaration], set.Set[fileinfo.Declaration]) {
+	mainFile := &fileinfo.FileInfo{Filename: "/root/main.go", ImportPath: "example.com/root"}
+	libFile := &fileinfo.FileInfo{Filename: "/root/lib/lib.go", ImportPath: "example.com/root/lib"}
+	main := fileinfo.Declaration{Parent: mainFile, Name: "main", Kind: fileinfo.KindFunc, Pos: token.Position{Line: 5}}
+	run := fileinfo.Declaration{Parent: libFile, Name: "Run", Kind: fileinfo.KindFunc, Pos: token.Position{Line: 3}}
+	config := fileinfo.Declaration{Parent: libFile, Name: "Config", Kind: fileinfo.KindType, Pos: token.Position{Line: 7}}
+	odd := fileinfo.Declaration{Parent: libFile, Name: `odd"name`, Kind: fileinfo.KindVar, Pos: token.Position{Line: 9}}
+
+	graph := references.NewReferenceGraph()
+	graph.AddReference(main, run, references.Reference{
+		Kind: references.KindCall,
+		Pos:  token.Position{Filename: "/root/main.go", Line: 6, Column: 6},
+	})
+	graph.AddReference(run, config, references.Reference{
+		Kind: references.KindCompositeLit,
+		Pos:  token.Position{Filename: "/root/lib/lib.go", Line: 4, Column: 2},
+	})
+	graph.AddReference(run, config, references.Reference{
+		Kind: references.KindTypeUse,
+		Pos:  token.Position{Filename: "/root/lib/lib.go", Line: 3, Column: 12},
+	})
+	graph.AddNode(odd)
+	return graph, set.NewSet(main), set.NewSet(odd)
+}
+
+func TestExport(t *testing.T) {
+	tests := map[string]struct {
+		format  Format
+		options []Option
+	}{
+		"export.dot":          {format: FormatDOT},
+		"export_packages.dot": {format: FormatDOT, options: []Option{WithCollapsePackages(true)}},
+		"export.graphml":      {format: FormatGraphML},
+		"export.mmd":          {format: FormatMermaid},
+		"export.json":         {format: FormatJSON},
+	}
+	for golden, test := range tests {
+		t.Run(golden, func(t *testing.T) {
+			graph, entrypoints, unreachable := exportGraph()
+			output := &bytes.Buffer{}
+			require.NoError(t, Export(output, test.format, graph, entrypoints, unreachable, test.options...))
+
+			path := filepath.Join("testdata", golden)
+			if *update {
+				require.NoError(t, os.WriteFile(path, output.Bytes(), 0o644))
+			}
+			expected, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, string(expected), output.String())
+		})
+	}
+}
+
+func TestExportCollapseUnsupported(t *testing.T) {
+	graph, entrypoints, unreachable := exportGraph()
+	err := Export(&bytes.Buffer{}, FormatJSON, graph, entrypoints, unreachable, WithCollapsePackages(true))
+	assert.Error(t, err)
+}
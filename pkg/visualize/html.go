@@ -0,0 +1,87 @@
+package visualize
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"io"
+
+	"github.com/crockeo/schoner/pkg/phases/fileinfo"
+	"github.com/crockeo/schoner/pkg/phases/references"
+	"github.com/crockeo/schoner/pkg/set"
+)
+
+//go:embed html.tmpl
+var htmlTemplateSource string
+
+var htmlTemplate = template.Must(template.New("visualize").Parse(htmlTemplateSource))
+
+type htmlGraph struct {
+	Nodes []htmlNode `json:"nodes"`
+	Edges []htmlEdge `json:"edges"`
+}
+
+type htmlNode struct {
+	Name        string `json:"name"`
+	Kind        string `json:"kind"`
+	Package     string `json:"package"`
+	File        string `json:"file"`
+	Line        int    `json:"line"`
+	Entrypoint  bool   `json:"entrypoint"`
+	Unreachable bool   `json:"unreachable"`
+}
+
+type htmlEdge struct {
+	From       int      `json:"from"`
+	To         int      `json:"to"`
+	References []string `json:"references"`
+}
+
+// VisualizeHTML writes a self-contained HTML page to `w`
+// which lays out and renders the reference graph in the browser.
+// The graph data is embedded in the page, so it can be opened without network access.
+func VisualizeHTML(
+	w io.Writer,
+	graph references.ReferenceGraph,
+	entrypoints set.Set[fileinfo.Declaration],
+	unreachable set.Set[fileinfo.Declaration],
+) error {
+	data := htmlGraph{
+		Nodes: []htmlNode{},
+		Edges: []htmlEdge{},
+	}
+
+	decls := graph.SortedNodes(fileinfo.Declaration.Less)
+	ids := map[fileinfo.Declaration]int{}
+	for i, decl := range decls {
+		ids[decl] = i
+		data.Nodes = append(data.Nodes, htmlNode{
+			Name:        decl.Name,
+			Kind:        decl.Kind.String(),
+			Package:     decl.Parent.ImportPath,
+			File:        decl.Parent.Filename,
+			Line:        decl.Pos.Line,
+			Entrypoint:  entrypoints.Contains(decl),
+			Unreachable: unreachable.Contains(decl),
+		})
+	}
+
+	for _, decl := range decls {
+		for _, peer := range graph.SortedChildren(decl, fileinfo.Declaration.Less) {
+			refs := []string{}
+			for _, ref := range graph.EdgeReferences(decl, peer) {
+				refs = append(refs, fmt.Sprintf("%s at line %d", ref.Kind, ref.Pos.Line))
+			}
+			data.Edges = append(data.Edges, htmlEdge{
+				From:       ids[decl],
+				To:         ids[peer],
+				References: refs,
+			})
+		}
+	}
+
+	if err := htmlTemplate.Execute(w, data); err != nil {
+		return fmt.Errorf("failed to render html: %w", err)
+	}
+	return nil
+}
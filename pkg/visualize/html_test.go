@@ -0,0 +1,37 @@
+package visualize
+
+import (
+	"bytes"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/crockeo/schoner/pkg/phases/fileinfo"
+	"github.com/crockeo/schoner/pkg/phases/references"
+	"github.com/crockeo/schoner/pkg/set"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVisualizeHTML(t *testing.T) {
+	fileInfo := &fileinfo.FileInfo{Filename: "/root/main.go", ImportPath: "example.com/root"}
+	main := fileinfo.Declaration{Parent: fileInfo, Name: "main", Kind: fileinfo.KindFunc, Pos: token.Position{Line: 3}}
+	evil := fileinfo.Declaration{Parent: fileInfo, Name: "</script><script>alert(1)</script>", Kind: fileinfo.KindFunc, Pos: token.Position{Line: 5}}
+	graph := references.NewReferenceGraph()
+	graph.AddReference(main, evil, references.Reference{Kind: references.KindCall, Pos: token.Position{Line: 3}})
+
+	output := &bytes.Buffer{}
+	require.NoError(t, VisualizeHTML(output, graph, set.NewSet(main), set.NewSet(evil)))
+	page := output.String()
+
+	assert.Contains(
+		t,
+		page,
+		`const graph = {"nodes":[`+
+			`{"name":"\u003c/script\u003e\u003cscript\u003ealert(1)\u003c/script\u003e","kind":"func","package":"example.com/root","file":"/root/main.go","line":5,"entrypoint":false,"unreachable":true},`+
+			`{"name":"main","kind":"func","package":"example.com/root","file":"/root/main.go","line":3,"entrypoint":true,"unreachable":false}`+
+			`],"edges":[{"from":1,"to":0,"references":["call at line 3"]}]};`,
+	)
+	// The only closing script tags are the page's own.
+	assert.Equal(t, 2, strings.Count(page, "</script>"))
+}
@@ -0,0 +1,147 @@
+//go:build cgo
+
+package visualize
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+
+	"github.com/crockeo/schoner/pkg/phases/fileinfo"
+	"github.com/crockeo/schoner/pkg/phases/references"
+	"github.com/crockeo/schoner/pkg/set"
+	"github.com/goccy/go-graphviz"
+	"github.com/goccy/go-graphviz/cgraph"
+)
+
+// Visualize renders the reference graph as an SVG through graphviz, and writes it to `w`.
+func Visualize(
+	w io.Writer,
+	graph references.ReferenceGraph,
+	entrypoints set.Set[fileinfo.Declaration],
+	unreachable set.Set[fileinfo.Declaration],
+	options ...Option,
+) error {
+	opts := visualizeOptions{}
+	WithOptions(options...)(&opts)
+
+	gviz := graphviz.New()
+	defer gviz.Close()
+	g, err := gviz.Graph(graphviz.Directed)
+	if err != nil {
+		return err
+	}
+	defer g.Close()
+
+	if opts.collapsePackages {
+		err = renderPackages(g, graph, entrypoints, unreachable)
+	} else {
+		err = renderDeclarations(g, graph, entrypoints, unreachable)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := gviz.Render(g, graphviz.SVG, w); err != nil {
+		return fmt.Errorf("failed to render svg: %w", err)
+	}
+	return nil
+}
+
+// renderDeclarations renders one node per declaration,
+// grouped into a cluster for each file within a cluster for each package.
+func renderDeclarations(
+	g *cgraph.Graph,
+	graph references.ReferenceGraph,
+	entrypoints set.Set[fileinfo.Declaration],
+	unreachable set.Set[fileinfo.Declaration],
+) error {
+	packageClusters := map[string]*cgraph.Graph{}
+	fileClusters := map[string]*cgraph.Graph{}
+	fileCluster := func(fileInfo *fileinfo.FileInfo) *cgraph.Graph {
+		if cluster, ok := fileClusters[fileInfo.Filename]; ok {
+			return cluster
+		}
+		packageCluster, ok := packageClusters[fileInfo.ImportPath]
+		if !ok {
+			// Graphviz only draws subgraphs whose names begin with "cluster".
+			packageCluster = g.SubGraph(fmt.Sprintf("cluster_%s", fileInfo.ImportPath), 1)
+			packageCluster.SetLabel(fileInfo.ImportPath)
+			packageCluster.SetStyle(cgraph.RoundedGraphStyle)
+			packageClusters[fileInfo.ImportPath] = packageCluster
+		}
+		cluster := packageCluster.SubGraph(fmt.Sprintf("cluster_%s", fileInfo.Filename), 1)
+		cluster.SetLabel(filepath.Base(fileInfo.Filename))
+		cluster.SetStyle(cgraph.DashedGraphStyle)
+		fileClusters[fileInfo.Filename] = cluster
+		return cluster
+	}
+
+	nodes := map[fileinfo.Declaration]*cgraph.Node{}
+	decls := graph.SortedNodes(fileinfo.Declaration.Less)
+	for _, decl := range decls {
+		node, err := fileCluster(decl.Parent).CreateNode(nodeID(decl))
+		if err != nil {
+			return err
+		}
+		node.SetLabel(decl.Name)
+		if fillColor := declarationFillColor(decl, entrypoints, unreachable); fillColor != "" {
+			node.SetStyle(cgraph.FilledNodeStyle)
+			node.SetFillColor(fillColor)
+		}
+		nodes[decl] = node
+	}
+
+	for _, decl := range decls {
+		for _, peer := range graph.SortedChildren(decl, fileinfo.Declaration.Less) {
+			edge, err := g.CreateEdge("", nodes[decl], nodes[peer])
+			if err != nil {
+				return err
+			}
+			edge.SetTooltip(edgeTooltip(graph.EdgeReferences(decl, peer)))
+		}
+	}
+	return nil
+}
+
+// renderPackages renders one node per package,
+// with edges weighted by the number of references from one package to another.
+func renderPackages(
+	g *cgraph.Graph,
+	graph references.ReferenceGraph,
+	entrypoints set.Set[fileinfo.Declaration],
+	unreachable set.Set[fileinfo.Declaration],
+) error {
+	packageNodes, packageEdges := collapsePackages(graph, entrypoints, unreachable)
+
+	nodes := map[string]*cgraph.Node{}
+	for _, packageNode := range packageNodes {
+		node, err := g.CreateNode(packageNode.ImportPath)
+		if err != nil {
+			return err
+		}
+		node.SetShape(cgraph.BoxShape)
+		node.SetLabel(fmt.Sprintf(
+			"%s\n%d declarations, %d unreachable",
+			packageNode.ImportPath,
+			packageNode.Declarations,
+			packageNode.Unreachable,
+		))
+		if fillColor := packageFillColor(packageNode); fillColor != "" {
+			node.SetStyle(cgraph.FilledNodeStyle)
+			node.SetFillColor(fillColor)
+		}
+		nodes[packageNode.ImportPath] = node
+	}
+
+	for _, packageEdge := range packageEdges {
+		edge, err := g.CreateEdge("", nodes[packageEdge.From], nodes[packageEdge.To])
+		if err != nil {
+			return err
+		}
+		edge.SetLabel(fmt.Sprintf("%d", packageEdge.References))
+		edge.SetPenWidth(1 + math.Log(float64(packageEdge.References)))
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+//go:build !cgo
+
+package visualize
+
+import (
+	"io"
+
+	"github.com/crockeo/schoner/pkg/phases/fileinfo"
+	"github.com/crockeo/schoner/pkg/phases/references"
+	"github.com/crockeo/schoner/pkg/set"
+)
+
+// Visualize renders SVGs through graphviz, which requires cgo.
+// Builds without cgo can still export any of the other formats.
+func Visualize(
+	w io.Writer,
+	graph references.ReferenceGraph,
+	entrypoints set.Set[fileinfo.Declaration],
+	unreachable set.Set[fileinfo.Declaration],
+	options ...Option,
+) error {
+	return ErrSVGRequiresCgo
+}
@@ -0,0 +1,48 @@
+//go:build cgo
+
+package visualize
+
+import (
+	"testing"
+
+	"github.com/crockeo/schoner/pkg/phases/fileinfo"
+	"github.com/goccy/go-graphviz"
+	"github.com/goccy/go-graphviz/cgraph"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// clusterNodes returns the names of the nodes in the cluster named `path[len(path)-1]`,
+// found by descending through the clusters named by the rest of `path`.
+func clusterNodes(t *testing.T, g *cgraph.Graph, path ...string) []string {
+	for _, name := range path {
+		g = g.SubGraph(name, 0)
+		require.NotNil(t, g.Agraph, name)
+	}
+	names := []string{}
+	for node := g.FirstNode(); node != nil; node = g.NextNode(node) {
+		names = append(names, node.Name())
+	}
+	return names
+}
+
+func TestRenderDeclarationsClusters(t *testing.T) {
+	graph, entrypoints, unreachable := exportGraph()
+	gviz := graphviz.New()
+	defer gviz.Close()
+	g, err := gviz.Graph(graphviz.Directed)
+	require.NoError(t, err)
+	defer g.Close()
+	require.NoError(t, renderDeclarations(g, graph, entrypoints, unreachable))
+
+	names := map[string][]string{}
+	for _, decl := range graph.SortedNodes(fileinfo.Declaration.Less) {
+		names[decl.Parent.Filename] = append(names[decl.Parent.Filename], nodeID(decl))
+	}
+
+	// Each file is a cluster within the cluster of its package.
+	assert.ElementsMatch(t, names["/root/main.go"], clusterNodes(t, g, "cluster_example.com/root", "cluster_/root/main.go"))
+	assert.ElementsMatch(t, names["/root/lib/lib.go"], clusterNodes(t, g, "cluster_example.com/root/lib", "cluster_/root/lib/lib.go"))
+	assert.ElementsMatch(t, names["/root/lib/lib.go"], clusterNodes(t, g, "cluster_example.com/root/lib"))
+	assert.Equal(t, "lib.go", g.SubGraph("cluster_example.com/root/lib", 0).SubGraph("cluster_/root/lib/lib.go", 0).Get("label"))
+}
@@ -1,51 +1,140 @@
 package visualize
 
 import (
-	"github.com/crockeo/schoner/pkg/astutil"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
 	"github.com/crockeo/schoner/pkg/graph"
 	"github.com/crockeo/schoner/pkg/phases/fileinfo"
+	"github.com/crockeo/schoner/pkg/phases/references"
 	"github.com/crockeo/schoner/pkg/set"
-	"github.com/goccy/go-graphviz"
-	"github.com/goccy/go-graphviz/cgraph"
 )
 
-func Visualize(
-	outputPath string,
-	graph graph.Graph[fileinfo.Declaration],
-	entrypoints set.Set[fileinfo.Declaration],
-	unreachable set.Set[fileinfo.Declaration],
-) error {
-	gviz := graphviz.New()
-	g, err := gviz.Graph(graphviz.Directed)
-	if err != nil {
-		return err
+var ErrSVGRequiresCgo = errors.New("rendering svg requires building with cgo; try --format dot instead")
+
+type visualizeOptions struct {
+	collapsePackages bool
+}
+
+type Option func(*visualizeOptions)
+
+// WithCollapsePackages renders one node per package instead of one node per declaration,
+// with each edge labeled by the number of references between the two packages.
+func WithCollapsePackages(collapsePackages bool) Option {
+	return func(vo *visualizeOptions) {
+		vo.collapsePackages = collapsePackages
+	}
+}
+
+func WithOptions(options ...Option) Option {
+	return func(vo *visualizeOptions) {
+		for _, option := range options {
+			option(vo)
+		}
 	}
+}
+
+// packageNode summarizes the declarations of a single package,
+// for visualizations which collapse packages into single nodes.
+type packageNode struct {
+	ImportPath   string
+	Declarations int
+	Unreachable  int
+	Entrypoints  int
+}
 
-	nodes := map[fileinfo.Declaration]*cgraph.Node{}
-	for decl := range graph {
-		node, err := g.CreateNode(astutil.Qualify(decl.Parent.Filename, decl.Name))
-		node.SetLabel(decl.Name)
+// packageEdge counts the references from the declarations of one package to those of another.
+type packageEdge struct {
+	graph.Edge[string]
+	References int
+}
+
+// collapsePackages summarizes the reference graph as a graph of packages,
+// returning nodes and edges sorted by import path.
+func collapsePackages(
+	referenceGraph references.ReferenceGraph,
+	entrypoints set.Set[fileinfo.Declaration],
+	unreachable set.Set[fileinfo.Declaration],
+) ([]packageNode, []packageEdge) {
+	nodesByPath := map[string]*packageNode{}
+	referenceCounts := map[graph.Edge[string]]int{}
+	for decl, peers := range referenceGraph.Graph {
+		importPath := decl.Parent.ImportPath
+		node, ok := nodesByPath[importPath]
+		if !ok {
+			node = &packageNode{ImportPath: importPath}
+			nodesByPath[importPath] = node
+		}
+		node.Declarations++
+		if unreachable.Contains(decl) {
+			node.Unreachable++
+		}
 		if entrypoints.Contains(decl) {
-			node.SetStyle(cgraph.FilledNodeStyle)
-			node.SetFillColor("chartreuse")
-		} else if unreachable.Contains(decl) {
-			node.SetStyle(cgraph.FilledNodeStyle)
-			node.SetFillColor("gray")
+			node.Entrypoints++
 		}
-		if err != nil {
-			return err
+
+		for peer := range peers {
+			if peer.Parent.ImportPath == importPath {
+				continue
+			}
+			edge := graph.Edge[string]{From: importPath, To: peer.Parent.ImportPath}
+			referenceCounts[edge] += len(referenceGraph.EdgeReferences(decl, peer))
 		}
-		nodes[decl] = node
 	}
 
-	for decl := range graph {
-		for peer := range graph[decl] {
-			_, err := g.CreateEdge("", nodes[decl], nodes[peer])
-			if err != nil {
-				return err
-			}
+	nodes := make([]packageNode, 0, len(nodesByPath))
+	for _, node := range nodesByPath {
+		nodes = append(nodes, *node)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].ImportPath < nodes[j].ImportPath
+	})
+
+	edges := make([]packageEdge, 0, len(referenceCounts))
+	for edge, count := range referenceCounts {
+		edges = append(edges, packageEdge{Edge: edge, References: count})
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].From != edges[j].From {
+			return edges[i].From < edges[j].From
 		}
+		return edges[i].To < edges[j].To
+	})
+	return nodes, edges
+}
+
+// packageFillColor returns the color used to fill a collapsed package's node,
+// or the empty string if it should not be filled.
+func packageFillColor(node packageNode) string {
+	if node.Entrypoints > 0 {
+		return "chartreuse"
+	} else if node.Unreachable == node.Declarations {
+		return "gray"
+	}
+	return ""
+}
+
+// declarationFillColor returns the color used to fill a declaration's node,
+// or the empty string if it should not be filled.
+func declarationFillColor(
+	decl fileinfo.Declaration,
+	entrypoints set.Set[fileinfo.Declaration],
+	unreachable set.Set[fileinfo.Declaration],
+) string {
+	if entrypoints.Contains(decl) {
+		return "chartreuse"
+	} else if unreachable.Contains(decl) {
+		return "gray"
 	}
+	return ""
+}
 
-	return gviz.RenderFilename(g, graphviz.SVG, outputPath)
+func edgeTooltip(refs []references.Reference) string {
+	lines := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		lines = append(lines, fmt.Sprintf("%s at %s", ref.Kind, ref.Pos))
+	}
+	return strings.Join(lines, "\n")
 }
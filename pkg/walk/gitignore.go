@@ -0,0 +1,116 @@
+package walk
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// gitignoreRule is a single pattern from a .gitignore file.
+// See https://git-scm.com/docs/gitignore#_pattern_format
+type gitignoreRule struct {
+	// pattern is a doublestar pattern relative to the directory containing the .gitignore.
+	pattern string
+	negate  bool
+	dirOnly bool
+}
+
+// parseGitignore parses the rules of a single .gitignore file.
+func parseGitignore(contents string) []gitignoreRule {
+	rules := []gitignoreRule{}
+	for _, line := range strings.Split(contents, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if !strings.HasSuffix(line, "\\ ") {
+			line = strings.TrimRight(line, " ")
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule := gitignoreRule{}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, "\\!") || strings.HasPrefix(line, "\\#") {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+		// Patterns with a slash anywhere but the end are relative to the .gitignore,
+		// while other patterns match at any depth.
+		if strings.Contains(line, "/") {
+			line = strings.TrimPrefix(line, "/")
+		} else {
+			line = "**/" + line
+		}
+		if line == "" || !doublestar.ValidatePattern(line) {
+			continue
+		}
+		rule.pattern = line
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// gitignoreMatcher decides whether paths beneath `root` are ignored by any .gitignore,
+// loading each directory's .gitignore the first time it's needed.
+type gitignoreMatcher struct {
+	root  string
+	rules map[string][]gitignoreRule
+}
+
+func newGitignoreMatcher(root string) *gitignoreMatcher {
+	return &gitignoreMatcher{
+		root:  root,
+		rules: map[string][]gitignoreRule{},
+	}
+}
+
+// ignored reports whether `rel`, a slash-separated path relative to the root, is ignored.
+// It doesn't consider whether any of the parents of `rel` are ignored,
+// since git never looks inside an ignored directory in the first place.
+func (gm *gitignoreMatcher) ignored(rel string, isDir bool) bool {
+	dirs := []string{}
+	for dir := path.Dir(rel); dir != "."; dir = path.Dir(dir) {
+		dirs = append(dirs, dir)
+	}
+	dirs = append(dirs, ".")
+
+	// Rules in deeper .gitignores take precedence, as do later rules within a single .gitignore,
+	// so visit them from the root down and let the last matching rule win.
+	ignored := false
+	for i := len(dirs) - 1; i >= 0; i-- {
+		dir := dirs[i]
+		target := rel
+		if dir != "." {
+			target = strings.TrimPrefix(rel, dir+"/")
+		}
+		for _, rule := range gm.load(dir) {
+			if rule.dirOnly && !isDir {
+				continue
+			}
+			if ok, _ := doublestar.Match(rule.pattern, target); ok {
+				ignored = !rule.negate
+			}
+		}
+	}
+	return ignored
+}
+
+func (gm *gitignoreMatcher) load(dir string) []gitignoreRule {
+	if rules, ok := gm.rules[dir]; ok {
+		return rules
+	}
+	contents, err := os.ReadFile(filepath.Join(gm.root, filepath.FromSlash(dir), ".gitignore"))
+	rules := []gitignoreRule{}
+	if err == nil {
+		rules = parseGitignore(string(contents))
+	}
+	gm.rules[dir] = rules
+	return rules
+}
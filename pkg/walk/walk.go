@@ -1,16 +1,28 @@
 package walk
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"github.com/bmatcuk/doublestar/v4"
 	"github.com/crockeo/schoner/pkg/set"
 )
 
 type walkFilesOptions struct {
 	ignoreDirs  set.Set[string]
 	ignoreTests bool
+	include     []string
+	exclude     []string
+	gitignore   bool
+	overlay     map[string][]byte
+}
+
+func newWalkFilesOptions(option Option) walkFilesOptions {
+	options := walkFilesOptions{ignoreDirs: set.NewSet[string]()}
+	option(&options)
+	return options
 }
 
 type Option func(*walkFilesOptions)
@@ -29,6 +41,40 @@ func WithIgnoreTests(ignoreTests bool) Option {
 	}
 }
 
+// WithInclude only visits files matching at least one of the glob `patterns`,
+// e.g. `pkg/**` or `*_handler.go`.
+// Patterns are matched against paths relative to the root, and those without a slash match a file's name at any depth.
+func WithInclude(patterns ...string) Option {
+	return func(wfo *walkFilesOptions) {
+		wfo.include = append(wfo.include, patterns...)
+	}
+}
+
+// WithExclude skips files and directories matching any of the glob `patterns`,
+// e.g. `gen/**` or `*.pb.go`.
+// Patterns are matched like in WithInclude.
+func WithExclude(patterns ...string) Option {
+	return func(wfo *walkFilesOptions) {
+		wfo.exclude = append(wfo.exclude, patterns...)
+	}
+}
+
+// WithGitignore skips files and directories which are ignored by a .gitignore beneath the root.
+func WithGitignore(gitignore bool) Option {
+	return func(wfo *walkFilesOptions) {
+		wfo.gitignore = gitignore
+	}
+}
+
+// WithOverlay replaces the contents of files on disk with the provided contents,
+// keyed by absolute path, when they are read through ReadFile.
+// This lets callers analyze files which have been edited but not yet saved.
+func WithOverlay(overlay map[string][]byte) Option {
+	return func(wfo *walkFilesOptions) {
+		wfo.overlay = overlay
+	}
+}
+
 func WithOptions(options ...Option) Option {
 	return func(wfo *walkFilesOptions) {
 		for _, option := range options {
@@ -40,13 +86,14 @@ func WithOptions(options ...Option) Option {
 // GoFiles walks through the directory `root` and calls the visitor on every `.go` file.
 // See available Options for more configuration.
 func GoFiles(root string, option Option, visitor func(path string) error) error {
-	options := walkFilesOptions{ignoreDirs: set.NewSet[string]()}
-	option(&options)
-
 	root, err := filepath.Abs(root)
 	if err != nil {
 		return err
 	}
+	filter, err := NewFilter(root, option)
+	if err != nil {
+		return err
+	}
 
 	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
@@ -54,18 +101,134 @@ func GoFiles(root string, option Option, visitor func(path string) error) error
 		}
 
 		if info.IsDir() {
-			if options.ignoreDirs.Contains(filepath.Base(path)) {
+			if !filter.includesDir(path) {
 				return filepath.SkipDir
 			}
 			return nil
 		}
 
-		if filepath.Ext(path) != ".go" {
-			return nil
-		}
-		if options.ignoreTests && strings.HasSuffix(path, "_test.go") {
+		if !filter.includesFile(path) {
 			return nil
 		}
 		return visitor(path)
 	})
 }
+
+// Filter decides which files beneath a root directory GoFiles would visit,
+// for callers which find files through some other means.
+type Filter struct {
+	root      string
+	options   walkFilesOptions
+	gitignore *gitignoreMatcher
+}
+
+// NewFilter validates the patterns in `option`, and prepares to filter files beneath `root`.
+func NewFilter(root string, option Option) (*Filter, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	options := newWalkFilesOptions(option)
+	for _, pattern := range append(append([]string{}, options.include...), options.exclude...) {
+		if !doublestar.ValidatePattern(pattern) {
+			return nil, fmt.Errorf("invalid glob pattern `%s`", pattern)
+		}
+	}
+	filter := &Filter{root: root, options: options}
+	if options.gitignore {
+		filter.gitignore = newGitignoreMatcher(root)
+	}
+	return filter, nil
+}
+
+// Includes reports whether GoFiles would visit `path`, which must be absolute.
+func (f *Filter) Includes(path string) bool {
+	rel, ok := f.relative(path)
+	if !ok {
+		return false
+	}
+	dir := f.root
+	for _, part := range strings.Split(rel, "/") {
+		if !f.includesDir(dir) {
+			return false
+		}
+		dir = filepath.Join(dir, part)
+	}
+	return f.includesFile(path)
+}
+
+// includesDir reports whether GoFiles would descend into `dir`,
+// assuming that it would descend into each of its parents.
+func (f *Filter) includesDir(dir string) bool {
+	rel, ok := f.relative(dir)
+	if !ok {
+		return false
+	}
+	// The root is always walked, even when it's named like an ignored directory, e.g. testdata.
+	if rel == "." {
+		return true
+	}
+	if f.options.ignoreDirs.Contains(filepath.Base(dir)) {
+		return false
+	}
+	if matchesAny(f.options.exclude, rel) {
+		return false
+	}
+	return f.gitignore == nil || !f.gitignore.ignored(rel, true)
+}
+
+// includesFile reports whether GoFiles would visit `path`,
+// assuming that it would descend into each of its parents.
+func (f *Filter) includesFile(path string) bool {
+	if filepath.Ext(path) != ".go" {
+		return false
+	}
+	if f.options.ignoreTests && strings.HasSuffix(path, "_test.go") {
+		return false
+	}
+	rel, ok := f.relative(path)
+	if !ok {
+		return false
+	}
+	if len(f.options.include) > 0 && !matchesAny(f.options.include, rel) {
+		return false
+	}
+	if matchesAny(f.options.exclude, rel) {
+		return false
+	}
+	return f.gitignore == nil || !f.gitignore.ignored(rel, false)
+}
+
+// relative returns `path` relative to the root, separated by slashes,
+// or false if it isn't beneath the root.
+func (f *Filter) relative(path string) (string, bool) {
+	rel, err := filepath.Rel(f.root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// matchesAny reports whether `rel` matches any of `patterns`.
+// Like in a .gitignore, patterns without a slash match the base name at any depth.
+func matchesAny(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		if !strings.Contains(pattern, "/") {
+			pattern = "**/" + pattern
+		}
+		if ok, _ := doublestar.Match(pattern, rel); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadFile reads the file at `path`,
+// preferring its contents from the overlay if one was provided.
+func ReadFile(path string, option Option) ([]byte, error) {
+	options := newWalkFilesOptions(option)
+	if contents, ok := options.overlay[path]; ok {
+		return contents, nil
+	}
+	return os.ReadFile(path)
+}
@@ -0,0 +1,111 @@
+package walk
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func goFiles(t *testing.T, root string, option Option) []string {
+	paths := []string{}
+	err := GoFiles(root, option, func(path string) error {
+		rel, err := filepath.Rel(root, path)
+		require.NoError(t, err)
+		paths = append(paths, filepath.ToSlash(rel))
+		return nil
+	})
+	require.NoError(t, err)
+	return paths
+}
+
+func TestGoFiles(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		".gitignore":      "# generated\n/build/\n*.gen.go\n!keep.gen.go\n",
+		"main.go":         "",
+		"main_test.go":    "",
+		"api.pb.go":       "",
+		"build/out.go":    "",
+		"gen/gen.go":      "",
+		"pkg/a.go":        "",
+		"pkg/a.gen.go":    "",
+		"pkg/keep.gen.go": "",
+		"pkg/.gitignore":  "local.go\n",
+		"pkg/local.go":    "",
+		"pkg/build/b.go":  "",
+		"pkg/vendor/v.go": "",
+		"pkg/notes.txt":   "",
+	}
+	for filename, contents := range files {
+		path := filepath.Join(root, filename)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(contents), 0o644))
+	}
+
+	assert.Equal(
+		t,
+		[]string{
+			"api.pb.go",
+			"build/out.go",
+			"gen/gen.go",
+			"main.go",
+			"main_test.go",
+			"pkg/a.gen.go",
+			"pkg/a.go",
+			"pkg/build/b.go",
+			"pkg/keep.gen.go",
+			"pkg/local.go",
+			"pkg/vendor/v.go",
+		},
+		goFiles(t, root, WithOptions()),
+	)
+
+	option := WithOptions(
+		WithIgnoreDirs("vendor"),
+		WithIgnoreTests(true),
+		WithGitignore(true),
+		WithExclude("gen/**", "*.pb.go"),
+	)
+	expected := []string{
+		"main.go",
+		"pkg/a.go",
+		"pkg/build/b.go",
+		"pkg/keep.gen.go",
+	}
+	assert.Equal(t, expected, goFiles(t, root, option))
+
+	filter, err := NewFilter(root, option)
+	require.NoError(t, err)
+	for filename := range files {
+		included := false
+		for _, path := range expected {
+			included = included || path == filename
+		}
+		assert.Equal(t, included, filter.Includes(filepath.Join(root, filename)), filename)
+	}
+
+	assert.Equal(t, []string{"pkg/a.go", "pkg/build/b.go", "pkg/keep.gen.go"}, goFiles(t, root, WithOptions(option, WithInclude("pkg/*.go", "b.go"))))
+
+	_, err = NewFilter(root, WithExclude("[unclosed"))
+	assert.Error(t, err)
+}
+
+func TestGoFilesIgnoredRoot(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "testdata")
+	for _, filename := range []string{"a.go", "testdata/b.go"} {
+		path := filepath.Join(root, filename)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, nil, 0o644))
+	}
+
+	// The root is walked even though it's named like an ignored directory, unlike the directories beneath it.
+	option := WithIgnoreDirs("testdata")
+	assert.Equal(t, []string{"a.go"}, goFiles(t, root, option))
+	filter, err := NewFilter(root, option)
+	require.NoError(t, err)
+	assert.True(t, filter.Includes(filepath.Join(root, "a.go")))
+	assert.False(t, filter.Includes(filepath.Join(root, "testdata/b.go")))
+}